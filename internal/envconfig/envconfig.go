@@ -0,0 +1,104 @@
+// Package envconfig reads the environment variables configuring otelginmetrics and otelhttpmetrics,
+// which each define their own variable names.
+package envconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lookup reads the environment variables which are set, passing their values to the set funcs.
+// Booleans are parsed by strconv.ParseBool, lists are separated by commas and durations are parsed
+// by time.ParseDuration. Invalid values are ignored and their errors are returned by Err
+type Lookup struct {
+	errs []error
+}
+
+// Bool reads a boolean
+func (l *Lookup) Bool(name string, set func(bool)) {
+	if value, ok := os.LookupEnv(name); ok {
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("%s: %w", name, err))
+			return
+		}
+		set(b)
+	}
+}
+
+// String reads a string, trimmed of its spaces
+func (l *Lookup) String(name string, set func(string)) {
+	if value, ok := os.LookupEnv(name); ok {
+		set(strings.TrimSpace(value))
+	}
+}
+
+// List reads a list of strings, dropping the empty items
+func (l *Lookup) List(name string, set func([]string)) {
+	if value, ok := os.LookupEnv(name); ok {
+		set(splitList(value))
+	}
+}
+
+// Durations reads a list of durations, the list is ignored when any of them is invalid
+func (l *Lookup) Durations(name string, set func([]time.Duration)) {
+	if value, ok := os.LookupEnv(name); ok {
+		if durations, ok := parseList(l, name, value, time.ParseDuration); ok {
+			set(durations)
+		}
+	}
+}
+
+// Floats reads a list of floats, the list is ignored when any of them is invalid
+func (l *Lookup) Floats(name string, set func([]float64)) {
+	if value, ok := os.LookupEnv(name); ok {
+		parseFloat := func(item string) (float64, error) { return strconv.ParseFloat(item, 64) }
+		if floats, ok := parseList(l, name, value, parseFloat); ok {
+			set(floats)
+		}
+	}
+}
+
+// Enum reads one of the keys of values, passing the matching value to set
+func Enum[T any](l *Lookup, name, kind string, values map[string]T, set func(T)) {
+	if value, ok := os.LookupEnv(name); ok {
+		v, found := values[strings.TrimSpace(value)]
+		if !found {
+			l.errs = append(l.errs, fmt.Errorf("%s: unknown %s %q", name, kind, value))
+			return
+		}
+		set(v)
+	}
+}
+
+// Err returns the errors of the invalid values read, joined
+func (l *Lookup) Err() error {
+	return errors.Join(l.errs...)
+}
+
+func parseList[T any](l *Lookup, name, value string, parse func(string) (T, error)) ([]T, bool) {
+	var items []T
+	for _, s := range splitList(value) {
+		item, err := parse(s)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("%s: %w", name, err))
+			return nil, false
+		}
+		items = append(items, item)
+	}
+	return items, items != nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
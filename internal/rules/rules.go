@@ -0,0 +1,256 @@
+// Package rules implements the declarative rules deciding which requests are recorded and the attributes
+// added to them, shared by otelginmetrics and otelhttpmetrics which expose them under their own names.
+package rules
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"gopkg.in/yaml.v2"
+)
+
+// The actions of the rules
+const (
+	// ActionInclude records the matching requests, with the attributes of the rule
+	ActionInclude = "include"
+	// ActionExclude does not record the matching requests
+	ActionExclude = "exclude"
+	// ActionAttributes adds the attributes of the rule to the matching requests, the default action
+	ActionAttributes = "attributes"
+)
+
+// Config is the declarative form of the rules, as loaded from YAML or JSON:
+//
+//	rules:
+//	  - match:
+//	      paths: ["/healthz", "/metrics"]
+//	    action: exclude
+//	  - match:
+//	      user_agent: "(?i)bot|crawler"
+//	    attributes:
+//	      client.kind: bot
+//
+// The rules are evaluated in order. The attributes of all the matching rules are added to the
+// attributes of the request, replacing those with the same key, and the first matching rule
+// including or excluding the request decides whether it is recorded. Requests which no rule
+// excludes are recorded.
+type Config struct {
+	Rules []RuleConfig `yaml:"rules"`
+}
+
+// RuleConfig is a rule, applying its action and attributes to the requests it matches
+type RuleConfig struct {
+	Match      MatchConfig       `yaml:"match"`
+	Action     string            `yaml:"action"`
+	Attributes map[string]string `yaml:"attributes"`
+}
+
+// MatchConfig selects the requests a rule applies to. A request matches when it matches every
+// field which is set, and a list matches when any of its items matches. Routes, paths and hosts
+// are globs as understood by path.Match, the regular expressions follow the regexp syntax.
+type MatchConfig struct {
+	Routes    []string          `yaml:"routes"`
+	Paths     []string          `yaml:"paths"`
+	PathRegex string            `yaml:"path_regex"`
+	Methods   []string          `yaml:"methods"`
+	Hosts     []string          `yaml:"hosts"`
+	Headers   map[string]string `yaml:"headers"`
+	UserAgent string            `yaml:"user_agent"`
+}
+
+// Rules are the compiled rules, safe for concurrent use
+type Rules struct {
+	rules []rule
+}
+
+type rule struct {
+	routes     []string
+	paths      []string
+	pathRegex  *regexp.Regexp
+	methods    []string
+	hosts      []string
+	headers    map[string]*regexp.Regexp
+	userAgent  *regexp.Regexp
+	action     string
+	attributes []attribute.KeyValue
+}
+
+// Provider provides the rules currently in effect, it is implemented by Rules and Watcher
+type Provider interface {
+	CurrentRules() *Rules
+}
+
+// CurrentRules returns the rules themselves
+func (r *Rules) CurrentRules() *Rules {
+	return r
+}
+
+// New compiles the rules of the config, an error is returned for invalid patterns or actions
+func New(config Config) (*Rules, error) {
+	rules := &Rules{rules: make([]rule, 0, len(config.Rules))}
+	for i, ruleConfig := range config.Rules {
+		r, err := newRule(ruleConfig)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		rules.rules = append(rules.rules, r)
+	}
+	return rules, nil
+}
+
+// Parse parses and compiles the rules from YAML or JSON
+func Parse(data []byte) (*Rules, error) {
+	var config Config
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, err
+	}
+	return New(config)
+}
+
+// Load reads, parses and compiles the rules from a YAML or JSON file
+func Load(filename string) (*Rules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return rules, nil
+}
+
+func newRule(config RuleConfig) (rule, error) {
+	r := rule{
+		routes:  config.Match.Routes,
+		paths:   config.Match.Paths,
+		methods: config.Match.Methods,
+		hosts:   config.Match.Hosts,
+		action:  config.Action,
+	}
+	switch r.action {
+	case "":
+		r.action = ActionAttributes
+	case ActionInclude, ActionExclude, ActionAttributes:
+	default:
+		return r, fmt.Errorf("unknown action %q", r.action)
+	}
+	for _, patterns := range [][]string{r.routes, r.paths, r.hosts} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return r, fmt.Errorf("pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	var err error
+	if r.pathRegex, err = compileRegexp(config.Match.PathRegex); err != nil {
+		return r, err
+	}
+	if r.userAgent, err = compileRegexp(config.Match.UserAgent); err != nil {
+		return r, err
+	}
+	if len(config.Match.Headers) > 0 {
+		r.headers = make(map[string]*regexp.Regexp, len(config.Match.Headers))
+		for name, expr := range config.Match.Headers {
+			if r.headers[name], err = regexp.Compile(expr); err != nil {
+				return r, err
+			}
+		}
+	}
+	for key, value := range config.Attributes {
+		r.attributes = append(r.attributes, attribute.String(key, value))
+	}
+	return r, nil
+}
+
+func compileRegexp(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
+
+// Evaluate returns the attributes the rules add to the request and whether it is to be recorded.
+// It is a func rather than a method so that it is not exported by the packages aliasing Rules
+func Evaluate(r *Rules, route string, request *http.Request) ([]attribute.KeyValue, bool) {
+	if r == nil {
+		return nil, true
+	}
+	var attrs []attribute.KeyValue
+	record, decided := true, false
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if !rule.matches(route, request) {
+			continue
+		}
+		attrs = append(attrs, rule.attributes...)
+		if decided {
+			continue
+		}
+		switch rule.action {
+		case ActionInclude:
+			record, decided = true, true
+		case ActionExclude:
+			record, decided = false, true
+		}
+	}
+	return attrs, record
+}
+
+func (r *rule) matches(route string, request *http.Request) bool {
+	var urlPath string
+	if request.URL != nil {
+		urlPath = request.URL.Path
+	}
+	switch {
+	case len(r.routes) > 0 && !matchGlobs(r.routes, route),
+		len(r.paths) > 0 && !matchGlobs(r.paths, urlPath),
+		r.pathRegex != nil && !r.pathRegex.MatchString(urlPath),
+		len(r.methods) > 0 && !matchMethod(r.methods, request.Method),
+		len(r.hosts) > 0 && !matchGlobs(r.hosts, requestHost(request)),
+		r.userAgent != nil && !r.userAgent.MatchString(request.UserAgent()):
+		return false
+	}
+	for name, expr := range r.headers {
+		if !expr.MatchString(request.Header.Get(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchGlobs(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestHost returns the host of the request without the port
+func requestHost(request *http.Request) string {
+	host := request.Host
+	if host == "" && request.URL != nil {
+		host = request.URL.Host
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
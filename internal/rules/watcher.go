@@ -0,0 +1,126 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// Watcher holds the rules loaded from a file and reloads them when the file changes.
+// The rules are swapped atomically, the requests being recorded keep the rules they started with
+type Watcher struct {
+	filename string
+	rules    atomic.Value
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+
+	reloads  metric.Int64Counter
+	failures metric.Int64Counter
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Watch loads the rules from the file and checks every interval whether the file changed,
+// to reload them. When the changed file cannot be loaded the current rules stay in effect.
+// The reloads and parse failures are counted using the meter, the instruments which cannot be
+// created are reported using otel.Handle and replaced by no-op ones.
+// An error is returned when the interval is not positive or the rules cannot be loaded initially
+func Watch(filename string, interval time.Duration, meter metric.Meter) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%s: non-positive watch interval %v", filename, interval)
+	}
+	reloads, reloadsErr := meter.Int64Counter("rules.reloads", metric.WithDescription("Number of times the rules were loaded"), metric.WithUnit("{reload}"))
+	failures, failuresErr := meter.Int64Counter("rules.parse_failures", metric.WithDescription("Number of times the rules could not be loaded"), metric.WithUnit("{failure}"))
+	if err := errors.Join(reloadsErr, failuresErr); err != nil {
+		otel.Handle(err)
+	}
+	if reloads == nil {
+		reloads = noop.Int64Counter{}
+	}
+	if failures == nil {
+		failures = noop.Int64Counter{}
+	}
+	w := &Watcher{
+		filename: filename,
+		reloads:  reloads,
+		failures: failures,
+		done:     make(chan struct{}),
+	}
+	if err := w.Reload(); err != nil {
+		return nil, err
+	}
+	go w.watch(interval)
+	return w, nil
+}
+
+// CurrentRules returns the rules last loaded
+func (w *Watcher) CurrentRules() *Rules {
+	rules, _ := w.rules.Load().(*Rules)
+	return rules
+}
+
+// Reload loads the rules from the file, the current rules stay in effect when it fails
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ctx := context.Background()
+	if info, err := os.Stat(w.filename); err == nil {
+		w.modTime, w.size = info.ModTime(), info.Size()
+	}
+	rules, err := Load(w.filename)
+	if err != nil {
+		w.failures.Add(ctx, 1)
+		return err
+	}
+	w.rules.Store(rules)
+	w.reloads.Add(ctx, 1)
+	return nil
+}
+
+// changed returns whether the file was modified since it was last loaded
+func (w *Watcher) changed() bool {
+	info, err := os.Stat(w.filename)
+	if err != nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !info.ModTime().Equal(w.modTime) || info.Size() != w.size
+}
+
+func (w *Watcher) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			if !w.changed() {
+				continue
+			}
+			if err := w.Reload(); err != nil {
+				otel.Handle(err)
+			}
+		}
+	}
+}
+
+// Close stops watching the file, the rules last loaded stay in effect
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	return nil
+}
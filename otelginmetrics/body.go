@@ -0,0 +1,85 @@
+package otelginmetrics
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestBody counts the bytes read from the body of a request by the handlers.
+// It may be read from another goroutine than the one of the middleware.
+type requestBody struct {
+	io.ReadCloser
+	size atomic.Int64
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size.Add(int64(n))
+	return n, err
+}
+
+// responseSize returns the number of body bytes written by the handlers,
+// -1 is returned by gin when nothing was written and HEAD responses have no body.
+func responseSize(w gin.ResponseWriter, method string) int64 {
+	if size := w.Size(); size > 0 && method != http.MethodHead {
+		return int64(size)
+	}
+	return 0
+}
+
+// requestHeaderSize returns the size of the request line and headers as sent over HTTP/1.1
+func requestHeaderSize(r *http.Request) int64 {
+	// METHOD URI PROTO\r\n ... \r\n
+	s := len(r.Method) + 1 + len(r.RequestURI) + 1 + len(r.Proto) + 2
+	if r.Host != "" {
+		s += len("Host: ") + len(r.Host) + 2
+	}
+	return int64(s) + headerSize(r.Header) + 2
+}
+
+// responseHeaderSize returns the size of the status line and headers as sent over HTTP/1.1.
+// The headers added by net/http, such as Date and Content-Length, are not counted.
+func responseHeaderSize(w gin.ResponseWriter, proto string) int64 {
+	status := w.Status()
+	// PROTO CODE TEXT\r\n ... \r\n
+	s := len(proto) + 1 + len(strconv.Itoa(status)) + 1 + len(http.StatusText(status)) + 2
+	return int64(s) + headerSize(w.Header()) + 2
+}
+
+func headerSize(header http.Header) int64 {
+	s := 0
+	for name, values := range header {
+		for _, value := range values {
+			// Name: value\r\n
+			s += len(name) + 2 + len(value) + 2
+		}
+	}
+	return int64(s)
+}
+
+// computeApproximateRequestSize approximates the size of the whole request from its path, method, protocol,
+// headers and host, plus the bytes read from its body, as the content length is -1 for chunked bodies
+func computeApproximateRequestSize(r *http.Request, bodySize int64) int64 {
+	s := 0
+	if r.URL != nil {
+		s = len(r.URL.Path)
+	}
+
+	s += len(r.Method)
+	s += len(r.Proto)
+	for name, values := range r.Header {
+		s += len(name)
+		for _, value := range values {
+			s += len(value)
+		}
+	}
+	s += len(r.Host)
+
+	// N.B. r.Form and r.MultipartForm are assumed to be included in r.URL.
+
+	return int64(s) + bodySize
+}
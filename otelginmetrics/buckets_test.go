@@ -0,0 +1,90 @@
+package otelginmetrics_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelginmetrics/otelginmetricstest"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// serveBuckets serves a request through a router using the middleware recording on the MeterProvider of the reader
+func serveBuckets(reader *otelginmetricstest.Reader, options ...otelginmetrics.Option) {
+	gin.SetMode(gin.ReleaseMode)
+	options = append(options, otelginmetrics.WithMeterProvider(reader.MeterProvider()))
+	router := gin.New()
+	router.Use(otelginmetrics.Middleware("test", options...))
+	router.POST("/", func(c *gin.Context) {})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello")))
+}
+
+func TestBucketBoundaries(t *testing.T) {
+	t.Run("old", func(t *testing.T) {
+		reader := otelginmetricstest.NewReader()
+		serveBuckets(reader, otelginmetrics.WithSemconvMode(otelginmetrics.SemconvOld),
+			otelginmetrics.WithDurationBuckets(otelginmetrics.LatencySensitiveDurationBuckets...),
+			otelginmetrics.WithSizeBuckets(otelginmetrics.LatencySensitiveSizeBuckets...))
+
+		// the old duration is recorded in milliseconds
+		if bounds := int64Bounds(t, reader, "http.server.duration"); fmt.Sprint(bounds) != "[1 2 5 10 20 50 100 200 500 1000]" {
+			t.Errorf("got duration boundaries %v", bounds)
+		}
+		if bounds := int64Bounds(t, reader, "http.server.request_content_length"); fmt.Sprint(bounds) != fmt.Sprint(otelginmetrics.LatencySensitiveSizeBuckets) {
+			t.Errorf("got size boundaries %v, want %v", bounds, otelginmetrics.LatencySensitiveSizeBuckets)
+		}
+	})
+
+	t.Run("stable", func(t *testing.T) {
+		// the duration boundaries of the stable conventions are advised by default, in seconds
+		reader := otelginmetricstest.NewReader()
+		serveBuckets(reader, otelginmetrics.WithSemconvMode(otelginmetrics.SemconvStable),
+			otelginmetrics.WithSizeBuckets(otelginmetrics.BatchSizeBuckets...))
+
+		data, ok := reader.Metric(t, "http.server.request.duration").Data.(metricdata.Histogram[float64])
+		if !ok || len(data.DataPoints) != 1 {
+			t.Fatalf("got duration %+v, want a histogram with a data point", data)
+		}
+		if bounds := data.DataPoints[0].Bounds; fmt.Sprint(bounds) != "[0.005 0.01 0.025 0.05 0.075 0.1 0.25 0.5 0.75 1 2.5 5 7.5 10]" {
+			t.Errorf("got duration boundaries %v", bounds)
+		}
+		if bounds := int64Bounds(t, reader, "http.server.request.body.size"); fmt.Sprint(bounds) != fmt.Sprint(otelginmetrics.BatchSizeBuckets) {
+			t.Errorf("got size boundaries %v, want %v", bounds, otelginmetrics.BatchSizeBuckets)
+		}
+	})
+}
+
+// int64Bounds returns the bucket boundaries of the histogram with the name
+func int64Bounds(t *testing.T, reader *otelginmetricstest.Reader, name string) []float64 {
+	t.Helper()
+	data, ok := reader.Metric(t, name).Data.(metricdata.Histogram[int64])
+	if !ok || len(data.DataPoints) != 1 {
+		t.Fatalf("got %s %+v, want a histogram with a data point", name, data)
+	}
+	return data.DataPoints[0].Bounds
+}
+
+func TestExponentialHistogramView(t *testing.T) {
+	reader := otelginmetricstest.NewReader(sdkmetric.WithView(otelginmetrics.ExponentialHistogramView()))
+	serveBuckets(reader, otelginmetrics.WithSemconvMode(otelginmetrics.SemconvDuplicate),
+		otelginmetrics.WithDurationBuckets(otelginmetrics.LatencySensitiveDurationBuckets...))
+
+	// the view takes precedence over the advised boundaries
+	for _, name := range []string{"http.server.duration", "http.server.request_content_length", "http.server.response_content_length", "http.server.request.body.size"} {
+		if data, ok := reader.Metric(t, name).Data.(metricdata.ExponentialHistogram[int64]); !ok || len(data.DataPoints) != 1 {
+			t.Errorf("got %s %T, want an exponential histogram with a data point", name, reader.Metric(t, name).Data)
+		}
+	}
+	if data, ok := reader.Metric(t, "http.server.request.duration").Data.(metricdata.ExponentialHistogram[float64]); !ok || len(data.DataPoints) != 1 {
+		t.Errorf("got the stable duration %T, want an exponential histogram with a data point", reader.Metric(t, "http.server.request.duration").Data)
+	}
+	// the counters are left as they are
+	if _, ok := reader.Metric(t, "http.server.request_count").Data.(metricdata.Sum[int64]); !ok {
+		t.Errorf("got the request count %T, want a sum", reader.Metric(t, "http.server.request_count").Data)
+	}
+}
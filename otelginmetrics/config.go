@@ -2,19 +2,40 @@ package otelginmetrics
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/technologize/otel-go-contrib/internal/rules"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type config struct {
-	recordInFlight bool
-	recordSize     bool
-	recordDuration bool
-	groupedStatus  bool
-	recorder       Recorder
-	attributes     func(serverName, route string, request *http.Request) []attribute.KeyValue
-	shouldRecord   func(serverName, route string, request *http.Request) bool
+	recordInFlight  bool
+	recordSize      bool
+	recordDuration  bool
+	groupedStatus   bool
+	recorder        Recorder
+	attributes      func(serverName, route string, request *http.Request) []attribute.KeyValue
+	shouldRecord    func(serverName, route string, request *http.Request) bool
+	semconvMode     SemconvMode
+	durationBuckets []time.Duration
+	sizeBuckets     []float64
+	meterProvider   metric.MeterProvider
+	recordHeaders   bool
+	recordPanics    bool
+	errorClassifier func(err error) string
+	tracerProvider  trace.TracerProvider
+	propagators     propagation.TextMapPropagator
+	envConfig       bool
+	disabled        bool
+	metricsPrefix   string
+	excludedPaths   []string
+	rules           RulesProvider
+	now             func() time.Time
 }
 
 func defaultConfig() *config {
@@ -23,7 +44,11 @@ func defaultConfig() *config {
 		recordDuration: true,
 		recordSize:     true,
 		groupedStatus:  true,
-		attributes:     DefaultAttributes,
+		semconvMode:    SemconvModeFromEnv(),
+		meterProvider:  otel.GetMeterProvider(),
+		now:            time.Now,
+		tracerProvider: otel.GetTracerProvider(),
+		propagators:    otel.GetTextMapPropagator(),
 		shouldRecord: func(_, _ string, _ *http.Request) bool {
 			return true
 		},
@@ -43,3 +68,40 @@ var DefaultAttributes = func(serverName, route string, request *http.Request) []
 	}
 	return attrs
 }
+
+// newRecorder returns the open telemetry recorder using the configured MeterProvider.
+// Errors creating the instruments are reported using otel.Handle
+func (cfg *config) newRecorder() Recorder {
+	recorder, err := newOtelRecorder(cfg.meterProvider, cfg.metricsPrefix, cfg)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return recorder
+}
+
+// recordRequest returns whether the request is to be recorded, following the excluded paths, the rules
+// and the shouldRecord func, along with the attributes the rules add to the request
+func (cfg *config) recordRequest(serverName, route string, request *http.Request) ([]attribute.KeyValue, bool) {
+	for _, path := range cfg.excludedPaths {
+		if route == path || (request.URL != nil && request.URL.Path == path) {
+			return nil, false
+		}
+	}
+	var attrs []attribute.KeyValue
+	if cfg.rules != nil {
+		var record bool
+		if attrs, record = rules.Evaluate(cfg.rules.CurrentRules(), route, request); !record {
+			return nil, false
+		}
+	}
+	return attrs, cfg.shouldRecord(serverName, route, request)
+}
+
+// requestAttributes returns the attributes of the request followed by those added by the rules,
+// which replace the attributes with the same key
+func (cfg *config) requestAttributes(serverName, route string, request *http.Request, ruleAttributes []attribute.KeyValue) []attribute.KeyValue {
+	// the slice returned by the attributes func is clipped, so that appending leaves its backing array untouched
+	attrs := cfg.attributes(serverName, route, request)
+	attrs = attrs[:len(attrs):len(attrs)]
+	return append(attrs, ruleAttributes...)
+}
@@ -0,0 +1,33 @@
+package otelginmetrics
+
+import (
+	"context"
+	"time"
+)
+
+// withoutCancel returns a context carrying the values of ctx which is never done.
+// The SDK drops measurements recorded with a done context, so measurements of
+// requests whose client went away are recorded with it.
+func withoutCancel(ctx context.Context) context.Context {
+	return withoutCancelCtx{ctx}
+}
+
+type withoutCancelCtx struct {
+	ctx context.Context
+}
+
+func (withoutCancelCtx) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (withoutCancelCtx) Done() <-chan struct{} {
+	return nil
+}
+
+func (withoutCancelCtx) Err() error {
+	return nil
+}
+
+func (c withoutCancelCtx) Value(key interface{}) interface{} {
+	return c.ctx.Value(key)
+}
@@ -0,0 +1,136 @@
+package otelginmetrics
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// FilterKeys returns a recorder dropping the attributes with the given keys before forwarding the measurements
+// to the recorder, e.g. http.server_name for a system which does not need it
+func FilterKeys(recorder Recorder, keys ...attribute.Key) Recorder {
+	drop := make(map[attribute.Key]bool, len(keys))
+	for _, key := range keys {
+		drop[key] = true
+	}
+	return &decorator{recorder: recorder, rewrite: func(attributes []attribute.KeyValue) []attribute.KeyValue {
+		filtered := make([]attribute.KeyValue, 0, len(attributes))
+		for _, attr := range attributes {
+			if !drop[attr.Key] {
+				filtered = append(filtered, attr)
+			}
+		}
+		return filtered
+	}}
+}
+
+// RenameKeys returns a recorder renaming the keys of the attributes found in renames before forwarding
+// the measurements to the recorder, e.g. http.target to url.path
+func RenameKeys(recorder Recorder, renames map[attribute.Key]attribute.Key) Recorder {
+	return &decorator{recorder: recorder, rewrite: func(attributes []attribute.KeyValue) []attribute.KeyValue {
+		renamed := make([]attribute.KeyValue, len(attributes))
+		for i, attr := range attributes {
+			if key, ok := renames[attr.Key]; ok {
+				attr.Key = key
+			}
+			renamed[i] = attr
+		}
+		return renamed
+	}}
+}
+
+// AddStaticAttributes returns a recorder adding the attributes to the measurements before forwarding them
+// to the recorder, e.g. deployment.environment. The attributes of the measurements with the same keys take precedence
+func AddStaticAttributes(recorder Recorder, static ...attribute.KeyValue) Recorder {
+	return &decorator{recorder: recorder, rewrite: func(attributes []attribute.KeyValue) []attribute.KeyValue {
+		added := make([]attribute.KeyValue, 0, len(static)+len(attributes))
+		added = append(added, static...)
+		return append(added, attributes...)
+	}}
+}
+
+// MapValues returns a recorder replacing the value of the attribute with the key by the one returned by mapper
+// before forwarding the measurements to the recorder, e.g. to bucket the values of an attribute
+func MapValues(recorder Recorder, key attribute.Key, mapper func(attribute.Value) attribute.Value) Recorder {
+	return &decorator{recorder: recorder, rewrite: func(attributes []attribute.KeyValue) []attribute.KeyValue {
+		mapped := make([]attribute.KeyValue, len(attributes))
+		for i, attr := range attributes {
+			if attr.Key == key {
+				attr.Value = mapper(attr.Value)
+			}
+			mapped[i] = attr
+		}
+		return mapped
+	}}
+}
+
+// decorator rewrites the attributes of the measurements before forwarding them to its recorder.
+// It implements the optional recorder interfaces, forwarded when its recorder implements them,
+// so that decorators can be stacked, and tells which ones its recorder implements as a wrapper
+type decorator struct {
+	recorder Recorder
+	// rewrite returns the rewritten attributes in a new slice, leaving the slice of the caller untouched
+	rewrite func(attributes []attribute.KeyValue) []attribute.KeyValue
+}
+
+func (d *decorator) wraps(implements func(Recorder) bool) bool {
+	return implements(d.recorder)
+}
+
+func (d *decorator) AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	d.recorder.AddRequests(ctx, quantity, d.rewrite(attributes))
+}
+
+func (d *decorator) ObserveHTTPRequestDuration(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	d.recorder.ObserveHTTPRequestDuration(ctx, duration, d.rewrite(attributes))
+}
+
+func (d *decorator) ObserveHTTPRequestSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	d.recorder.ObserveHTTPRequestSize(ctx, sizeBytes, d.rewrite(attributes))
+}
+
+func (d *decorator) ObserveHTTPRequestBodySize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	if recorder, ok := d.recorder.(RequestBodySizeRecorder); ok {
+		recorder.ObserveHTTPRequestBodySize(ctx, sizeBytes, d.rewrite(attributes))
+	}
+}
+
+func (d *decorator) ObserveHTTPResponseSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	d.recorder.ObserveHTTPResponseSize(ctx, sizeBytes, d.rewrite(attributes))
+}
+
+func (d *decorator) AddInflightRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	d.recorder.AddInflightRequests(ctx, quantity, d.rewrite(attributes))
+}
+
+func (d *decorator) ObserveHTTPRequestHeaderSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	if recorder, ok := d.recorder.(HeaderSizeRecorder); ok {
+		recorder.ObserveHTTPRequestHeaderSize(ctx, sizeBytes, d.rewrite(attributes))
+	}
+}
+
+func (d *decorator) ObserveHTTPResponseHeaderSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	if recorder, ok := d.recorder.(HeaderSizeRecorder); ok {
+		recorder.ObserveHTTPResponseHeaderSize(ctx, sizeBytes, d.rewrite(attributes))
+	}
+}
+
+func (d *decorator) AddPanics(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	if recorder, ok := d.recorder.(PanicRecorder); ok {
+		recorder.AddPanics(ctx, quantity, d.rewrite(attributes))
+	}
+}
+
+func (d *decorator) AddHandlerErrors(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	if recorder, ok := d.recorder.(HandlerErrorRecorder); ok {
+		recorder.AddHandlerErrors(ctx, quantity, d.rewrite(attributes))
+	}
+}
+
+func (d *decorator) RecordRequest(ctx context.Context, summary RequestSummary) {
+	if recorder, ok := d.recorder.(SummaryRecorder); ok {
+		summary.Attributes = d.rewrite(summary.Attributes)
+		recorder.RecordRequest(ctx, summary)
+	}
+}
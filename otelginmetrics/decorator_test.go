@@ -0,0 +1,87 @@
+package otelginmetrics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelginmetrics/otelginmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestDecorators(t *testing.T) {
+	attributes := []attribute.KeyValue{
+		attribute.String("http.host", "example.com"),
+		attribute.String("http.target", "/users/42"),
+		attribute.String("deployment.environment", "staging"),
+	}
+	for _, tt := range []struct {
+		name     string
+		decorate func(otelginmetrics.Recorder) otelginmetrics.Recorder
+		want     []attribute.KeyValue
+	}{
+		{
+			name: "FilterKeys",
+			decorate: func(recorder otelginmetrics.Recorder) otelginmetrics.Recorder {
+				return otelginmetrics.FilterKeys(recorder, "http.host", "unknown")
+			},
+			want: []attribute.KeyValue{attributes[1], attributes[2]},
+		},
+		{
+			name: "RenameKeys",
+			decorate: func(recorder otelginmetrics.Recorder) otelginmetrics.Recorder {
+				return otelginmetrics.RenameKeys(recorder, map[attribute.Key]attribute.Key{"http.target": "url.path"})
+			},
+			want: []attribute.KeyValue{attributes[0], attribute.String("url.path", "/users/42"), attributes[2]},
+		},
+		{
+			name: "AddStaticAttributes",
+			decorate: func(recorder otelginmetrics.Recorder) otelginmetrics.Recorder {
+				return otelginmetrics.AddStaticAttributes(recorder, attribute.String("deployment.environment", "prod"), attribute.String("service.name", "api"))
+			},
+			// the attributes of the measurements take precedence over the static ones
+			want: append([]attribute.KeyValue{attribute.String("service.name", "api")}, attributes...),
+		},
+		{
+			name: "MapValues",
+			decorate: func(recorder otelginmetrics.Recorder) otelginmetrics.Recorder {
+				return otelginmetrics.MapValues(recorder, "http.target", func(attribute.Value) attribute.Value {
+					return attribute.StringValue("/users/:id")
+				})
+			},
+			want: []attribute.KeyValue{attributes[0], attribute.String("http.target", "/users/:id"), attributes[2]},
+		},
+		{
+			name: "stacked",
+			decorate: func(recorder otelginmetrics.Recorder) otelginmetrics.Recorder {
+				return otelginmetrics.FilterKeys(otelginmetrics.RenameKeys(recorder, map[attribute.Key]attribute.Key{"http.target": "url.path"}), "http.host")
+			},
+			want: []attribute.KeyValue{attribute.String("url.path", "/users/42"), attributes[2]},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := otelginmetricstest.NewRecorder()
+			decorated := tt.decorate(recorder)
+			passed := append([]attribute.KeyValue(nil), attributes...)
+			decorated.AddRequests(context.Background(), 1, passed)
+			// the optional interfaces of the recorder are forwarded as well
+			decorated.(otelginmetrics.PanicRecorder).AddPanics(context.Background(), 1, passed)
+
+			want := attribute.NewSet(tt.want...)
+			for _, m := range recorder.Measurements() {
+				if !m.Attributes.Equals(&want) {
+					t.Errorf("%s: got attributes %v, want %v", m.Kind, m.Attributes.ToSlice(), tt.want)
+				}
+			}
+			if n := len(recorder.Measurements()); n != 2 {
+				t.Errorf("got %d measurements, want 2", n)
+			}
+			for i := range attributes {
+				if passed[i] != attributes[i] {
+					t.Errorf("the attributes passed were modified: got %v, want %v", passed, attributes)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package otelginmetrics_test
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelginmetrics/otelginmetricstest"
+	"go.opentelemetry.io/otel"
+)
+
+// serveEnv sends a request of each path through a router using the middleware with the options
+func serveEnv(options []otelginmetrics.Option, paths ...string) {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(otelginmetrics.Middleware("test", options...))
+	for _, path := range paths {
+		router.GET(path, func(c *gin.Context) {})
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+}
+
+// captureErrors returns a func returning the errors reported using otel.Handle while the test runs.
+// The handler returned by otel.GetErrorHandler is the global one, which delegates to the handler set
+// and would delegate to itself once set back, so the errors are logged as by default once the test completed
+func captureErrors(t *testing.T) func() []error {
+	var mu sync.Mutex
+	var errs []error
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+	t.Cleanup(func() {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) { log.Print(err) }))
+	})
+	return func() []error {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]error(nil), errs...)
+	}
+}
+
+func TestEnvConfig(t *testing.T) {
+	t.Setenv(otelginmetrics.EnvRecordInFlight, "false")
+	t.Setenv(otelginmetrics.EnvExcludedPaths, "/healthz, /metrics")
+	t.Setenv(otelginmetrics.EnvMetricsPrefix, "myapp")
+
+	recorder := otelginmetricstest.NewRecorder()
+	serveEnv([]otelginmetrics.Option{otelginmetrics.WithRecorder(recorder), otelginmetrics.WithEnvConfig()}, "/users", "/healthz")
+	recorder.AssertRequestCount(t, "/users", http.StatusOK, 1)
+	recorder.AssertRequestCount(t, "/healthz", http.StatusOK, 0)
+	if inflight := recorder.Filter(otelginmetricstest.KindInflight, nil); len(inflight) != 0 {
+		t.Errorf("expected no request in flight to be recorded, got %v", inflight)
+	}
+
+	reader := otelginmetricstest.NewReader()
+	serveEnv([]otelginmetrics.Option{otelginmetrics.WithMeterProvider(reader.MeterProvider()), otelginmetrics.WithEnvConfig()}, "/users")
+	if count, _ := reader.Histogram(t, "myapp.http.server.duration"); count != 1 {
+		t.Errorf("expected the duration to be recorded with the prefix of the environment, got %d data points", count)
+	}
+
+	// the environment is not read without WithEnvConfig
+	recorder = otelginmetricstest.NewRecorder()
+	serveEnv([]otelginmetrics.Option{otelginmetrics.WithRecorder(recorder)}, "/healthz")
+	recorder.AssertRequestCount(t, "/healthz", http.StatusOK, 1)
+}
+
+func TestEnvConfigPrecedence(t *testing.T) {
+	t.Setenv(otelginmetrics.EnvRecordInFlight, "true")
+	t.Setenv(otelginmetrics.EnvExcludedPaths, "/healthz")
+
+	// the options take precedence over the environment, whatever their order
+	recorder := otelginmetricstest.NewRecorder()
+	serveEnv([]otelginmetrics.Option{
+		otelginmetrics.WithRecordInFlightDisabled(),
+		otelginmetrics.WithEnvConfig(),
+		otelginmetrics.WithExcludedPaths("/metrics"),
+		otelginmetrics.WithRecorder(recorder),
+	}, "/healthz", "/metrics")
+	recorder.AssertRequestCount(t, "/healthz", http.StatusOK, 1)
+	recorder.AssertRequestCount(t, "/metrics", http.StatusOK, 0)
+	if inflight := recorder.Filter(otelginmetricstest.KindInflight, nil); len(inflight) != 0 {
+		t.Errorf("expected no request in flight to be recorded, got %v", inflight)
+	}
+}
+
+func TestEnvConfigDisabled(t *testing.T) {
+	t.Setenv(otelginmetrics.EnvDisabled, "true")
+	recorder := otelginmetricstest.NewRecorder()
+	serveEnv([]otelginmetrics.Option{otelginmetrics.WithRecorder(recorder), otelginmetrics.WithEnvConfig()}, "/users")
+	if measurements := recorder.Measurements(); len(measurements) != 0 {
+		t.Errorf("expected nothing to be recorded, got %v", measurements)
+	}
+}
+
+func TestEnvConfigInvalid(t *testing.T) {
+	reported := captureErrors(t)
+	t.Setenv(otelginmetrics.EnvRecordSize, "maybe")
+	t.Setenv(otelginmetrics.EnvSemconvMode, "newest")
+
+	// the invalid values are reported and ignored
+	recorder := otelginmetricstest.NewRecorder()
+	serveEnv([]otelginmetrics.Option{otelginmetrics.WithRecorder(recorder), otelginmetrics.WithEnvConfig()}, "/users")
+	if sizes := recorder.Filter(otelginmetricstest.KindRequestSize, nil); len(sizes) != 1 {
+		t.Errorf("expected the request size to be recorded by default, got %v", sizes)
+	}
+	if errs := reported(); len(errs) != 1 {
+		t.Errorf("expected the invalid values to be reported together, got %v", errs)
+	}
+}
@@ -0,0 +1,44 @@
+package otelginmetrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrorTypeKey is the attribute describing the class of error a request failed with
+const ErrorTypeKey = attribute.Key("error.type")
+
+// ErrorTypePanic is the error type of the requests whose handler panicked
+const ErrorTypePanic = "panic"
+
+// GinErrorTypeKey is the attribute describing the gin.ErrorType of the errors added to the gin context by the handlers
+const GinErrorTypeKey = attribute.Key("gin.error.type")
+
+// ginErrorType returns the name of the gin.ErrorType, other is returned for combinations of types
+func ginErrorType(errorType gin.ErrorType) string {
+	switch errorType {
+	case gin.ErrorTypeBind:
+		return "bind"
+	case gin.ErrorTypeRender:
+		return "render"
+	case gin.ErrorTypePrivate:
+		return "private"
+	case gin.ErrorTypePublic:
+		return "public"
+	}
+	return "other"
+}
+
+// handlerErrorAttributes returns the attributes of an error added to the gin context, the error.type
+// attribute is set when an error classifier is configured and it returns a type for the error
+func (cfg *config) handlerErrorAttributes(err *gin.Error, attributes []attribute.KeyValue) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(attributes)+2)
+	attrs = append(attrs, attributes...)
+	attrs = append(attrs, GinErrorTypeKey.String(ginErrorType(err.Type)))
+	if cfg.errorClassifier != nil {
+		if errorType := cfg.errorClassifier(err.Err); errorType != "" {
+			attrs = append(attrs, ErrorTypeKey.String(errorType))
+		}
+	}
+	return attrs
+}
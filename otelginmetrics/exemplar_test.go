@@ -0,0 +1,54 @@
+package otelginmetrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelginmetrics/otelginmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextRecorder keeps the span context of the contexts the requests are recorded with,
+// which the SDK takes the trace and span IDs of the exemplars from
+type contextRecorder struct {
+	*otelginmetricstest.Recorder
+	mu    sync.Mutex
+	spans []trace.SpanContext
+}
+
+func (r *contextRecorder) AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	r.mu.Lock()
+	r.spans = append(r.spans, trace.SpanContextFromContext(ctx))
+	r.mu.Unlock()
+	r.Recorder.AddRequests(ctx, quantity, attributes)
+}
+
+func TestMiddlewareExemplarContext(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	recorder := &contextRecorder{Recorder: otelginmetricstest.NewRecorder()}
+	router := gin.New()
+	// the tracing middleware runs after the metrics one, the span is only in the context left by the handlers
+	router.Use(otelginmetrics.Middleware("test", otelginmetrics.WithRecorder(recorder)))
+	router.Use(otelginmetrics.Tracing("test", otelginmetrics.WithTracerProvider(provider)))
+	router.GET("/", func(c *gin.Context) {})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	ended := spans.Ended()
+	if len(ended) != 1 || len(recorder.spans) != 1 {
+		t.Fatalf("got %d spans and %d requests recorded, want 1 and 1", len(ended), len(recorder.spans))
+	}
+	if got, want := recorder.spans[0], ended[0].SpanContext(); got.TraceID() != want.TraceID() || got.SpanID() != want.SpanID() {
+		t.Errorf("recorded with the span %s/%s, want %s/%s", got.TraceID(), got.SpanID(), want.TraceID(), want.SpanID())
+	}
+}
@@ -0,0 +1,69 @@
+package otelginmetrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+var errInstrument = errors.New("instrument not created")
+
+// failingMeterProvider returns meters failing to create any instrument
+type failingMeterProvider struct{ noop.MeterProvider }
+
+func (failingMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return failingMeter{}
+}
+
+type failingMeter struct{ noop.Meter }
+
+func (failingMeter) Int64Counter(string, ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return nil, errInstrument
+}
+
+func (failingMeter) Int64UpDownCounter(string, ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	return nil, errInstrument
+}
+
+func (failingMeter) Int64Histogram(string, ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return nil, errInstrument
+}
+
+func (failingMeter) Float64Histogram(string, ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return nil, errInstrument
+}
+
+func (failingMeter) Int64ObservableUpDownCounter(string, ...metric.Int64ObservableUpDownCounterOption) (metric.Int64ObservableUpDownCounter, error) {
+	return nil, errInstrument
+}
+
+func TestNewRecorderFailingInstruments(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	// the instruments of the old conventions, the stable ones and both
+	for _, optIn := range []string{"", "http", "http/dup"} {
+		t.Setenv(otelginmetrics.SemconvStabilityOptInEnv, optIn)
+		recorder, err := otelginmetrics.NewRecorder(failingMeterProvider{}, "")
+		if !errors.Is(err, errInstrument) {
+			t.Fatalf("got error %v, want %v", err, errInstrument)
+		}
+		if recorder == nil {
+			t.Fatal("got no recorder along with the error")
+		}
+
+		// the recorder does not record, but it is safe to use
+		router := gin.New()
+		router.Use(otelginmetrics.Middleware("test", otelginmetrics.WithRecorder(recorder), otelginmetrics.WithRecordHeaderSize(), otelginmetrics.WithRecordPanics()))
+		router.POST("/", func(c *gin.Context) {
+			_ = c.Error(errInstrument)
+			c.String(http.StatusOK, "ok")
+		})
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("hello")))
+	}
+}
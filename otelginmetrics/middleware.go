@@ -2,39 +2,52 @@ package otelginmetrics
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// Middleware returns middleware that will trace incoming requests.
+// Middleware returns middleware that will record metrics of incoming requests.
+// Tracing returns the middleware starting the spans of the requests.
 // The service parameter should describe the name of the (virtual)
 // server handling the request.
 func Middleware(service string, options ...Option) gin.HandlerFunc {
-	cfg := defaultConfig()
-	for _, option := range options {
-		option.apply(cfg)
+	cfg := newConfig(options)
+	if cfg.disabled {
+		return func(ginCtx *gin.Context) {
+			ginCtx.Next()
+		}
+	}
+	if cfg.attributes == nil {
+		cfg.attributes = attributesForMode(cfg.semconvMode)
 	}
 	recorder := cfg.recorder
 	if recorder == nil {
-		recorder = GetRecorder("")
+		recorder = cfg.newRecorder()
 	}
 	return func(ginCtx *gin.Context) {
 
-		ctx := ginCtx.Request.Context()
+		ctx := withoutCancel(ginCtx.Request.Context())
 
 		route := ginCtx.FullPath()
 		if len(route) <= 0 {
 			route = "nonconfigured"
 		}
-		if !cfg.shouldRecord(service, route, ginCtx.Request) {
+		ruleAttributes, record := cfg.recordRequest(service, route, ginCtx.Request)
+		if !record {
 			ginCtx.Next()
 			return
 		}
 
-		start := time.Now()
-		reqAttributes := cfg.attributes(service, route, ginCtx.Request)
+		start := cfg.now()
+		request := ginCtx.Request
+		reqAttributes := cfg.requestAttributes(service, route, request, ruleAttributes)
+
+		var body *requestBody
+		if cfg.recordSize && request.Body != nil {
+			body = &requestBody{ReadCloser: request.Body}
+			request.Body = body
+		}
 
 		if cfg.recordInFlight {
 			recorder.AddInflightRequests(ctx, 1, reqAttributes)
@@ -43,52 +56,79 @@ func Middleware(service string, options ...Option) gin.HandlerFunc {
 
 		defer func() {
 
-			resAttributes := append(reqAttributes[0:0], reqAttributes...)
+			// a panic going up to a recovery middleware outside of this one leaves the status unset,
+			// the request is recorded as failed and the panic continues once recorded
+			panicked := recover()
+			if panicked != nil {
+				defer panic(panicked)
+			}
 
-			if cfg.groupedStatus {
-				code := int(ginCtx.Writer.Status()/100) * 100
-				resAttributes = append(resAttributes, semconv.HTTPStatusCodeKey.Int(code))
+			// handlers further down the chain, such as the Tracing middleware, may have replaced the
+			// request context with one carrying their span, which links the measurements to it as exemplars
+			ctx := withoutCancel(ginCtx.Request.Context())
+
+			status := ginCtx.Writer.Status()
+			// the response attributes get their own slice, the request attributes may share the backing array
+			// of the slice returned by the attributes func
+			resAttributes := make([]attribute.KeyValue, len(reqAttributes), len(reqAttributes)+3)
+			copy(resAttributes, reqAttributes)
+			if panicked != nil {
+				status = http.StatusInternalServerError
+				resAttributes = append(resAttributes, cfg.statusCodeAttributes(status)...)
+				resAttributes = append(resAttributes, ErrorTypeKey.String(ErrorTypePanic))
+				if panicRecorder, ok := optional[PanicRecorder](recorder); ok && cfg.recordPanics {
+					panicRecorder.AddPanics(ctx, 1, resAttributes)
+				}
 			} else {
-				resAttributes = append(resAttributes, semconv.HTTPAttributesFromHTTPStatusCode(ginCtx.Writer.Status())...)
+				resAttributes = append(resAttributes, cfg.statusCodeAttributes(status)...)
 			}
 
 			recorder.AddRequests(ctx, 1, resAttributes)
 
+			if errorRecorder, ok := optional[HandlerErrorRecorder](recorder); ok {
+				for _, err := range ginCtx.Errors {
+					errorRecorder.AddHandlerErrors(ctx, 1, cfg.handlerErrorAttributes(err, resAttributes))
+				}
+			}
+
+			var requestSize, resSize int64
 			if cfg.recordSize {
-				requestSize := computeApproximateRequestSize(ginCtx.Request)
-				recorder.ObserveHTTPRequestSize(ctx, requestSize, resAttributes)
-				recorder.ObserveHTTPResponseSize(ctx, int64(ginCtx.Writer.Size()), resAttributes)
+				if body != nil {
+					requestSize = body.size.Load()
+				}
+				resSize = responseSize(ginCtx.Writer, request.Method)
+				recorder.ObserveHTTPRequestSize(ctx, computeApproximateRequestSize(request, requestSize), resAttributes)
+				if bodySizeRecorder, ok := optional[RequestBodySizeRecorder](recorder); ok {
+					bodySizeRecorder.ObserveHTTPRequestBodySize(ctx, requestSize, resAttributes)
+				}
+				recorder.ObserveHTTPResponseSize(ctx, resSize, resAttributes)
 			}
 
+			if headerRecorder, ok := optional[HeaderSizeRecorder](recorder); ok && cfg.recordHeaders {
+				headerRecorder.ObserveHTTPRequestHeaderSize(ctx, requestHeaderSize(request), resAttributes)
+				headerRecorder.ObserveHTTPResponseHeaderSize(ctx, responseHeaderSize(ginCtx.Writer, request.Proto), resAttributes)
+			}
+
+			duration := cfg.now().Sub(start)
 			if cfg.recordDuration {
-				recorder.ObserveHTTPRequestDuration(ctx, time.Since(start), resAttributes)
+				recorder.ObserveHTTPRequestDuration(ctx, duration, resAttributes)
+			}
+
+			if summaryRecorder, ok := optional[SummaryRecorder](recorder); ok {
+				summaryRecorder.RecordRequest(ctx, RequestSummary{
+					Route:        route,
+					Method:       request.Method,
+					Status:       status,
+					StartTime:    start,
+					Duration:     duration,
+					RequestSize:  requestSize,
+					ResponseSize: resSize,
+					Error:        handlerError(panicked, ginCtx.Errors),
+					Attributes:   resAttributes,
+				})
 			}
 		}()
 
 		ginCtx.Next()
 	}
 }
-
-func computeApproximateRequestSize(r *http.Request) int64 {
-	s := 0
-	if r.URL != nil {
-		s = len(r.URL.Path)
-	}
-
-	s += len(r.Method)
-	s += len(r.Proto)
-	for name, values := range r.Header {
-		s += len(name)
-		for _, value := range values {
-			s += len(value)
-		}
-	}
-	s += len(r.Host)
-
-	// N.B. r.Form and r.MultipartForm are assumed to be included in r.URL.
-
-	if r.ContentLength != -1 {
-		s += int(r.ContentLength)
-	}
-	return int64(s)
-}
@@ -0,0 +1,169 @@
+package otelginmetrics_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelginmetrics/otelginmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMiddlewareChunkedRequestSize(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	recorder := otelginmetricstest.NewRecorder()
+	router := gin.New()
+	router.Use(otelginmetrics.Middleware("test", otelginmetrics.WithRecorder(recorder)))
+	router.POST("/upload", func(c *gin.Context) {
+		_, _ = io.Copy(io.Discard, c.Request.Body)
+		c.Status(http.StatusNoContent)
+	})
+
+	// a body of unknown length is sent chunked, with a content length of -1
+	request := httptest.NewRequest(http.MethodPost, "/upload", io.NopCloser(strings.NewReader(strings.Repeat("a", 1000))))
+	request.ContentLength = -1
+	request.TransferEncoding = []string{"chunked"}
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	if sizes := recorder.Filter(otelginmetricstest.KindRequestBodySize, nil); len(sizes) != 1 || sizes[0].Value != 1000 {
+		t.Errorf("expected the 1000 bytes read from the chunked body to be recorded, got %v", sizes)
+	}
+	if sizes := recorder.Filter(otelginmetricstest.KindResponseSize, nil); len(sizes) != 1 || sizes[0].Value != 0 {
+		t.Errorf("expected the response without body to be recorded with 0 bytes, got %v", sizes)
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	recorder := otelginmetricstest.NewRecorder()
+	router := gin.New()
+	// the recovery middleware sits outside of ours, the status is not set yet when the panic goes through it
+	router.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ interface{}) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.Use(otelginmetrics.Middleware("test", otelginmetrics.WithRecorder(recorder), otelginmetrics.WithRecordPanics()))
+	router.GET("/panic", func(c *gin.Context) {
+		panic("handler failed")
+	})
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if response.Code != http.StatusInternalServerError {
+		t.Errorf("expected the recovery middleware to respond 500, got %d", response.Code)
+	}
+	recorder.AssertRequestCount(t, "/panic", http.StatusInternalServerError, 1)
+	panics := recorder.Filter(otelginmetricstest.KindPanics, nil)
+	if len(panics) != 1 || panics[0].Route() != "/panic" {
+		t.Fatalf("expected a panic of /panic to be recorded, got %v", panics)
+	}
+	if errorType, _ := panics[0].Attributes.Value(otelginmetrics.ErrorTypeKey); errorType.AsString() != otelginmetrics.ErrorTypePanic {
+		t.Errorf("expected error.type=%s, got %q", otelginmetrics.ErrorTypePanic, errorType.AsString())
+	}
+	if inflight := recorder.Inflight(); inflight != 0 {
+		t.Errorf("expected no request in flight once the panic was recovered, got %d", inflight)
+	}
+}
+
+func TestMiddlewareHandlerErrors(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	errNotFound := errors.New("not found")
+	reader := otelginmetricstest.NewReader()
+	recorder, err := otelginmetrics.NewRecorder(reader.MeterProvider(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.Use(otelginmetrics.Middleware("test", otelginmetrics.WithRecorder(recorder), otelginmetrics.WithErrorClassifier(func(err error) string {
+		if errors.Is(err, errNotFound) {
+			return "not_found"
+		}
+		return ""
+	})))
+	router.GET("/users/:id", func(c *gin.Context) {
+		_ = c.Error(errors.New("invalid id")).SetType(gin.ErrorTypeBind)
+		_ = c.Error(errNotFound).SetType(gin.ErrorTypePublic)
+		_ = c.Error(errNotFound).SetType(gin.ErrorTypeBind | gin.ErrorTypeRender)
+		// the errors are private by default
+		_ = c.Error(errors.New("lookup failed"))
+		c.Status(http.StatusNotFound)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	const name = "http.server.handler_errors"
+	for _, tt := range []struct {
+		attributes []attribute.KeyValue
+		want       float64
+	}{
+		{nil, 4},
+		{[]attribute.KeyValue{attribute.String("http.route", "/users/:id")}, 4},
+		{[]attribute.KeyValue{otelginmetrics.GinErrorTypeKey.String("bind")}, 1},
+		{[]attribute.KeyValue{otelginmetrics.GinErrorTypeKey.String("public"), otelginmetrics.ErrorTypeKey.String("not_found")}, 1},
+		// combinations of types are counted as other
+		{[]attribute.KeyValue{otelginmetrics.GinErrorTypeKey.String("other"), otelginmetrics.ErrorTypeKey.String("not_found")}, 1},
+		{[]attribute.KeyValue{otelginmetrics.GinErrorTypeKey.String("private")}, 1},
+		{[]attribute.KeyValue{otelginmetrics.ErrorTypeKey.String("not_found")}, 2},
+	} {
+		if got := reader.Sum(t, name, tt.attributes...); got != tt.want {
+			t.Errorf("%v: got %v errors, want %v", tt.attributes, got, tt.want)
+		}
+	}
+	// the errors the classifier returns no type for have no error.type attribute
+	for _, point := range reader.Metric(t, name).Data.(metricdata.Sum[int64]).DataPoints {
+		if errorType, ok := point.Attributes.Value(otelginmetrics.ErrorTypeKey); ok && errorType.AsString() != "not_found" {
+			t.Errorf("got error.type %q, want it left out", errorType.AsString())
+		}
+		if ginErrorType, _ := point.Attributes.Value(otelginmetrics.GinErrorTypeKey); !point.Attributes.HasValue(otelginmetrics.ErrorTypeKey) &&
+			ginErrorType.AsString() != "bind" && ginErrorType.AsString() != "private" {
+			t.Errorf("got no error.type for the %s error classified as not_found", ginErrorType.AsString())
+		}
+	}
+}
+
+func TestMiddlewareSharedAttributes(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	// the attributes func returns the same slice for all the requests, with spare capacity
+	shared := make([]attribute.KeyValue, 1, 8)
+	shared[0] = attribute.String("service", "test")
+	attributes := func(_, _ string, _ *http.Request) []attribute.KeyValue { return shared }
+	recorder := otelginmetricstest.NewRecorder()
+	router := gin.New()
+	router.Use(otelginmetrics.Middleware("test", otelginmetrics.WithRecorder(recorder), otelginmetrics.WithAttributes(attributes)))
+	router.GET("/:status", func(c *gin.Context) {
+		status, _ := strconv.Atoi(c.Param("status"))
+		c.Status(status)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		status := []int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError}[i%3]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/"+strconv.Itoa(status), nil))
+		}()
+	}
+	wg.Wait()
+
+	if spare := shared[:cap(shared)][1]; spare != (attribute.KeyValue{}) {
+		t.Errorf("the spare capacity of the attributes was written with %v", spare)
+	}
+	// each request is recorded with its own status
+	for _, status := range []int{200, 400, 500} {
+		if n := len(recorder.Filter(otelginmetricstest.KindRequests, func(m otelginmetricstest.Measurement) bool { return m.Status() == status })); n == 0 {
+			t.Errorf("no request recorded with the status %d", status)
+		}
+	}
+	if n := len(recorder.Filter(otelginmetricstest.KindRequests, nil)); n != 50 {
+		t.Errorf("got %d requests recorded, want 50", n)
+	}
+}
@@ -0,0 +1,104 @@
+package otelginmetrics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelginmetrics/otelginmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// blockingRecorder records the requests once released
+type blockingRecorder struct {
+	*otelginmetricstest.Recorder
+	release chan struct{}
+}
+
+func (r *blockingRecorder) AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	<-r.release
+	r.Recorder.AddRequests(ctx, quantity, attributes)
+}
+
+func TestMultiRecorderCloseDrainsQueues(t *testing.T) {
+	recorder := &blockingRecorder{Recorder: otelginmetricstest.NewRecorder(), release: make(chan struct{})}
+	multi := otelginmetrics.MultiRecorder(otelginmetrics.Backend(recorder, otelginmetrics.WithBackendQueue(4)))
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		multi.AddRequests(ctx, 1, nil)
+	}
+	close(recorder.release)
+	if err := multi.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if n := len(recorder.Filter(otelginmetricstest.KindRequests, nil)); n != 4 {
+		t.Errorf("expected the 4 queued requests to be recorded on close, got %d", n)
+	}
+
+	// measurements following Close are recorded synchronously
+	multi.AddRequests(ctx, 1, nil)
+	if n := len(recorder.Filter(otelginmetricstest.KindRequests, nil)); n != 5 {
+		t.Errorf("expected the request following close to be recorded, got %d requests", n)
+	}
+	if err := multi.Close(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+}
+
+// panickingRecorder panics on every request
+type panickingRecorder struct {
+	*otelginmetricstest.Recorder
+}
+
+func (panickingRecorder) AddRequests(context.Context, int64, []attribute.KeyValue) {
+	panic("recorder failed")
+}
+
+func TestMultiRecorderFanOut(t *testing.T) {
+	reported := captureErrors(t)
+
+	first, second := otelginmetricstest.NewRecorder(), otelginmetricstest.NewRecorder()
+	multi := otelginmetrics.MultiRecorder(
+		first,
+		panickingRecorder{otelginmetricstest.NewRecorder()},
+		otelginmetrics.Backend(second, otelginmetrics.WithBackendAttributeFilter(attribute.NewDenyKeysFilter("secret"))),
+	)
+	multi.AddRequests(context.Background(), 1, []attribute.KeyValue{attribute.String("route", "/"), attribute.String("secret", "token")})
+
+	if n := len(first.Filter(otelginmetricstest.KindRequests, nil)); n != 1 {
+		t.Errorf("expected the first recorder to record the request, got %d", n)
+	}
+	requests := second.Filter(otelginmetricstest.KindRequests, nil)
+	if len(requests) != 1 {
+		t.Fatalf("expected the recorder following the panicking one to record the request, got %d", len(requests))
+	}
+	if _, ok := requests[0].Attributes.Value("secret"); ok {
+		t.Errorf("expected the filtered attribute to be dropped, got %v", requests[0].Attributes)
+	}
+	if errs := reported(); len(errs) != 1 {
+		t.Errorf("expected the panic to be reported, got %v", errs)
+	}
+}
+
+func TestMultiRecorderQueueFullDrops(t *testing.T) {
+	reported := captureErrors(t)
+
+	recorder := &blockingRecorder{Recorder: otelginmetricstest.NewRecorder(), release: make(chan struct{})}
+	multi := otelginmetrics.MultiRecorder(otelginmetrics.Backend(recorder, otelginmetrics.WithBackendQueue(1)))
+	// the recorder being blocked, at most one request is being recorded and one queued
+	for i := 0; i < 3; i++ {
+		multi.AddRequests(context.Background(), 1, nil)
+	}
+	close(recorder.release)
+	if err := multi.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if n := len(recorder.Filter(otelginmetricstest.KindRequests, nil)); n == 0 || n > 2 {
+		t.Errorf("expected 1 or 2 requests to be recorded while the queue was full, got %d", n)
+	}
+	if len(reported()) == 0 {
+		t.Error("expected the dropped requests to be reported")
+	}
+}
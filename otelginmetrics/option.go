@@ -2,8 +2,12 @@ package otelginmetrics
 
 import (
 	"net/http"
+	"time"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Option applies a configuration to the given config
@@ -18,7 +22,7 @@ func (fn optionFunc) apply(cfg *config) {
 }
 
 // WithAttributes sets a func using which what attributes to be recorded can be specified.
-// By default the DefaultAttributes is used
+// By default the DefaultAttributes is used, or StableAttributes following the SemconvMode
 func WithAttributes(attributes func(serverName, route string, request *http.Request) []attribute.KeyValue) Option {
 	return optionFunc(func(cfg *config) {
 		cfg.attributes = attributes
@@ -50,6 +54,7 @@ func WithRecordSizeDisabled() Option {
 }
 
 // WithGroupedStatus determines whether to group the response status codes or not. If true 2xx, 3xx will be stored
+// Only the old http.status_code attribute is grouped, the stable http.response.status_code is always the exact code
 // By default the groupedStatus is true
 func WithGroupedStatusDisabled() Option {
 	return optionFunc(func(cfg *config) {
@@ -72,3 +77,125 @@ func WithShouldRecordFunc(shouldRecord func(serverName, route string, request *h
 		cfg.shouldRecord = shouldRecord
 	})
 }
+
+// WithSemconvMode sets which HTTP semantic conventions the metric names and attributes follow
+// By default the mode is read from the OTEL_SEMCONV_STABILITY_OPT_IN environment variable
+func WithSemconvMode(mode SemconvMode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.semconvMode = mode
+	})
+}
+
+// WithDurationBuckets sets the bucket boundaries advised for the duration histogram.
+// LatencySensitiveDurationBuckets and BatchDurationBuckets can be used as presets
+// By default the SDK boundaries are used, or the ones of the stable HTTP semantic conventions
+func WithDurationBuckets(buckets ...time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.durationBuckets = buckets
+	})
+}
+
+// WithSizeBuckets sets the bucket boundaries in bytes advised for the request and response size histograms.
+// LatencySensitiveSizeBuckets and BatchSizeBuckets can be used as presets
+// By default the SDK boundaries are used
+func WithSizeBuckets(buckets ...float64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sizeBuckets = buckets
+	})
+}
+
+// WithMeterProvider sets the MeterProvider used to create the open telemetry recorder
+// By default the global MeterProvider is used
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.meterProvider = provider
+	})
+}
+
+// WithRecordHeaderSize determines whether to record the size of the request and response headers,
+// separately from the size of the bodies, when the recorder implements HeaderSizeRecorder
+// By default the size of the headers is not recorded
+func WithRecordHeaderSize() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.recordHeaders = true
+	})
+}
+
+// WithTracerProvider sets the TracerProvider used by the Tracing middleware to start the spans
+// By default the global TracerProvider is used
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.tracerProvider = provider
+	})
+}
+
+// WithPropagators sets the propagators used by the Tracing middleware to extract the trace context of the requests.
+// By default the global TextMapPropagator is used, as by the transport of otelhttpmetrics
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.propagators = propagators
+	})
+}
+
+// WithRecordPanics determines whether to count the requests whose handler panicked in a dedicated counter,
+// with the attributes of the request, when the recorder implements PanicRecorder.
+// The requests are recorded as failed with error.type set to panic in any case
+// By default the panics are not counted
+func WithRecordPanics() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.recordPanics = true
+	})
+}
+
+// WithErrorClassifier sets a func mapping the errors added to the gin context by the handlers to the error.type
+// attribute of the handler errors counter. It should return one of a bounded set of values, or "" to leave it out
+// By default the handler errors are only broken down by gin.ErrorType
+func WithErrorClassifier(classifier func(err error) string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.errorClassifier = classifier
+	})
+}
+
+// WithEnvConfig determines whether to read the configuration from the environment variables, see EnvDisabled
+// and the variables following it. The environment variables take precedence over the defaults
+// and the options take precedence over the environment variables
+// By default the environment variables are not read, except OTEL_SEMCONV_STABILITY_OPT_IN
+func WithEnvConfig() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.envConfig = true
+	})
+}
+
+// WithMetricsPrefix sets the prefix of the metric names of the open telemetry recorder, e.g. myapp.http.server.duration
+// By default the metric names are not prefixed
+func WithMetricsPrefix(prefix string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.metricsPrefix = prefix
+	})
+}
+
+// WithExcludedPaths sets the routes or paths of the requests which are not recorded, in addition to WithShouldRecordFunc
+// By default no path is excluded
+func WithExcludedPaths(paths ...string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.excludedPaths = paths
+	})
+}
+
+// WithRules sets the declarative rules including, excluding or adding attributes to the requests, in addition
+// to WithShouldRecordFunc. The rules are either fixed, see ParseRules, or reloaded from a file, see WatchRules
+// By default no rules are applied
+func WithRules(rules RulesProvider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.rules = rules
+	})
+}
+
+// WithClock sets the func returning the current time, used to measure the duration of the requests.
+// It makes the durations deterministic in tests
+// By default time.Now is used
+func WithClock(now func() time.Time) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.now = now
+	})
+}
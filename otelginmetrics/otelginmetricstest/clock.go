@@ -0,0 +1,36 @@
+package otelginmetricstest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a clock advanced manually, safe for concurrent use.
+// Its Now method is passed to the middleware using WithClock, for deterministic durations:
+//
+//	clock := otelginmetricstest.NewClock(time.Time{})
+//	router.Use(otelginmetrics.Middleware("test", otelginmetrics.WithClock(clock.Now)))
+//	router.GET("/slow", func(c *gin.Context) { clock.Advance(time.Second) })
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock set to the given time
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by the duration
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
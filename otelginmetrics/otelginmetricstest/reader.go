@@ -0,0 +1,127 @@
+package otelginmetricstest
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// Reader collects the data points recorded by the open telemetry recorder, using a MeterProvider
+// of the SDK with a ManualReader. The MeterProvider is passed to the middleware using WithMeterProvider
+type Reader struct {
+	reader   *sdkmetric.ManualReader
+	provider *sdkmetric.MeterProvider
+}
+
+// NewReader returns a Reader with a new MeterProvider, the options are passed to the MeterProvider, e.g. views
+func NewReader(options ...sdkmetric.Option) *Reader {
+	reader := sdkmetric.NewManualReader()
+	return &Reader{
+		reader:   reader,
+		provider: sdkmetric.NewMeterProvider(append(options, sdkmetric.WithReader(reader))...),
+	}
+}
+
+// MeterProvider returns the MeterProvider whose data points are collected
+func (r *Reader) MeterProvider() metric.MeterProvider {
+	return r.provider
+}
+
+// Collect returns the metrics recorded so far, failing the test when they cannot be collected
+func (r *Reader) Collect(t testing.TB) metricdata.ResourceMetrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := r.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect metrics: %v", err)
+	}
+	return rm
+}
+
+// Metric returns the metric with the name, failing the test when it was not recorded
+func (r *Reader) Metric(t testing.TB, name string) metricdata.Metrics {
+	t.Helper()
+	for _, sm := range r.Collect(t).ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	t.Fatalf("metric %s not recorded", name)
+	return metricdata.Metrics{}
+}
+
+// Sum returns the total of the data points of the counter or gauge with the name whose attributes
+// include the given ones, failing the test when it was not recorded
+func (r *Reader) Sum(t testing.TB, name string, attrs ...attribute.KeyValue) float64 {
+	t.Helper()
+	var sum float64
+	switch data := r.Metric(t, name).Data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range data.DataPoints {
+			if hasAttributes(dp.Attributes, attrs) {
+				sum += float64(dp.Value)
+			}
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range data.DataPoints {
+			if hasAttributes(dp.Attributes, attrs) {
+				sum += dp.Value
+			}
+		}
+	case metricdata.Gauge[int64]:
+		for _, dp := range data.DataPoints {
+			if hasAttributes(dp.Attributes, attrs) {
+				sum += float64(dp.Value)
+			}
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range data.DataPoints {
+			if hasAttributes(dp.Attributes, attrs) {
+				sum += dp.Value
+			}
+		}
+	default:
+		t.Fatalf("metric %s is a %T, not a sum", name, data)
+	}
+	return sum
+}
+
+// Histogram returns the count and the sum of the data points of the histogram with the name whose attributes
+// include the given ones, failing the test when it was not recorded
+func (r *Reader) Histogram(t testing.TB, name string, attrs ...attribute.KeyValue) (count uint64, sum float64) {
+	t.Helper()
+	switch data := r.Metric(t, name).Data.(type) {
+	case metricdata.Histogram[int64]:
+		for _, dp := range data.DataPoints {
+			if hasAttributes(dp.Attributes, attrs) {
+				count += dp.Count
+				sum += float64(dp.Sum)
+			}
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range data.DataPoints {
+			if hasAttributes(dp.Attributes, attrs) {
+				count += dp.Count
+				sum += dp.Sum
+			}
+		}
+	default:
+		t.Fatalf("metric %s is a %T, not a histogram", name, data)
+	}
+	return count, sum
+}
+
+// hasAttributes returns whether the set includes all the attributes
+func hasAttributes(set attribute.Set, attrs []attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if value, ok := set.Value(attr.Key); !ok || value != attr.Value {
+			return false
+		}
+	}
+	return true
+}
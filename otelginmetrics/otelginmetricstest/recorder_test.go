@@ -0,0 +1,127 @@
+package otelginmetricstest_test
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelginmetrics/otelginmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// fakeTB records the failures of the helpers, Fatalf stops the goroutine like testing.T does
+type fakeTB struct {
+	testing.TB
+	mu       sync.Mutex
+	failures []string
+}
+
+func (t *fakeTB) Helper() {}
+
+func (t *fakeTB) Errorf(format string, args ...interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeTB) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// check runs the helper against a fakeTB in its own goroutine, as Fatalf ends it,
+// and returns the failures reported by the helper
+func check(helper func(t testing.TB)) []string {
+	tb := &fakeTB{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		helper(tb)
+	}()
+	<-done
+	return tb.failures
+}
+
+func request(route string, status int) []attribute.KeyValue {
+	return []attribute.KeyValue{semconv.HTTPRouteKey.String(route), semconv.HTTPStatusCodeKey.Int(status)}
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := otelginmetricstest.NewRecorder()
+	ctx := context.Background()
+	recorder.AddInflightRequests(ctx, 1, request("/users", 0)[:1])
+	recorder.AddRequests(ctx, 1, request("/users", 200))
+	recorder.AddRequests(ctx, 1, request("/users", 200))
+	recorder.AddRequests(ctx, 1, request("/users", 500))
+	recorder.ObserveHTTPRequestDuration(ctx, time.Second, request("/users", 200))
+	recorder.AddPanics(ctx, 1, request("/users", 500))
+
+	if n := recorder.RequestCount("/users", 200); n != 2 {
+		t.Errorf("expected 2 requests with status 200, got %d", n)
+	}
+	if inflight := recorder.Inflight(); inflight != 1 {
+		t.Errorf("expected 1 request in flight, got %d", inflight)
+	}
+	panics := recorder.Filter(otelginmetricstest.KindPanics, func(m otelginmetricstest.Measurement) bool { return m.Status() == 500 })
+	if len(panics) != 1 || panics[0].Route() != "/users" {
+		t.Errorf("expected the panic to be recorded, got %v", panics)
+	}
+
+	if failures := check(func(t testing.TB) { recorder.AssertRequestCount(t, "/users", 500, 1) }); len(failures) != 0 {
+		t.Errorf("expected the request count to match, got %v", failures)
+	}
+	failures := check(func(t testing.TB) { recorder.AssertRequestCount(t, "/users", 200, 3) })
+	if len(failures) != 1 || !strings.Contains(failures[0], "got 2, want 3") {
+		t.Errorf("expected the request count mismatch to be reported, got %v", failures)
+	}
+	if failures := check(func(t testing.TB) { recorder.AssertDurationRecorded(t, "/users", time.Second) }); len(failures) != 0 {
+		t.Errorf("expected the duration to be found, got %v", failures)
+	}
+	if failures := check(func(t testing.TB) { recorder.AssertDurationRecorded(t, "/orders", time.Second) }); len(failures) != 1 {
+		t.Errorf("expected the duration of another route to be reported missing, got %v", failures)
+	}
+
+	recorder.Reset()
+	if measurements := recorder.Measurements(); len(measurements) != 0 {
+		t.Errorf("expected no measurements after reset, got %v", measurements)
+	}
+}
+
+func TestReader(t *testing.T) {
+	reader := otelginmetricstest.NewReader()
+	recorder, err := otelginmetrics.NewRecorder(reader.MeterProvider(), "")
+	if err != nil {
+		t.Fatalf("new recorder: %v", err)
+	}
+	ctx := context.Background()
+	recorder.AddRequests(ctx, 2, request("/users", 200))
+	recorder.AddRequests(ctx, 1, request("/users", 500))
+	recorder.ObserveHTTPRequestDuration(ctx, 20*time.Millisecond, request("/users", 200))
+	recorder.ObserveHTTPRequestDuration(ctx, 30*time.Millisecond, request("/users", 200))
+
+	if sum := reader.Sum(t, "http.server.request_count", semconv.HTTPStatusCodeKey.Int(200)); sum != 2 {
+		t.Errorf("expected 2 requests with status 200, got %v", sum)
+	}
+	if count, sum := reader.Histogram(t, "http.server.duration"); count != 2 || sum != 50 {
+		t.Errorf("expected 2 durations summing to 50ms, got %d summing to %v", count, sum)
+	}
+
+	failures := check(func(t testing.TB) { reader.Metric(t, "http.server.unknown") })
+	if len(failures) != 1 || !strings.Contains(failures[0], "not recorded") {
+		t.Errorf("expected the missing metric to be reported, got %v", failures)
+	}
+	failures = check(func(t testing.TB) { reader.Histogram(t, "http.server.request_count") })
+	if len(failures) != 1 || !strings.Contains(failures[0], "not a histogram") {
+		t.Errorf("expected the counter read as a histogram to be reported, got %v", failures)
+	}
+	failures = check(func(t testing.TB) { reader.Sum(t, "http.server.duration") })
+	if len(failures) != 1 || !strings.Contains(failures[0], "not a sum") {
+		t.Errorf("expected the histogram read as a sum to be reported, got %v", failures)
+	}
+}
@@ -15,56 +15,173 @@ const instrumentationName = "github.com/technologize/otel-go-contrib/otelginmetr
 // has the required methods to be used with the HTTP
 // middlewares.
 type otelRecorder struct {
+	semconvMode           SemconvMode
 	attemptsCounter       metric.Int64UpDownCounter
 	totalDuration         metric.Int64Histogram
 	activeRequestsCounter metric.Int64UpDownCounter
 	requestSize           metric.Int64Histogram
 	responseSize          metric.Int64Histogram
+	requestHeaderSize     metric.Int64Histogram
+	responseHeaderSize    metric.Int64Histogram
+
+	// instruments following the stable HTTP semantic conventions
+	requestDuration          metric.Float64Histogram
+	requestBodySize          metric.Int64Histogram
+	responseBodySize         metric.Int64Histogram
+	stableRequestHeaderSize  metric.Int64Histogram
+	stableResponseHeaderSize metric.Int64Histogram
+
+	// counters of failures which are the same in both conventions
+	panicsCounter        metric.Int64Counter
+	handlerErrorsCounter metric.Int64Counter
 }
 
+// GetRecorder returns the open telemetry recorder used by the middleware.
+// The global MeterProvider is used, errors creating the instruments are reported using otel.Handle
 func GetRecorder(metricsPrefix string) Recorder {
+	recorder, err := NewRecorder(otel.GetMeterProvider(), metricsPrefix)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return recorder
+}
+
+// NewRecorder returns the open telemetry recorder using the given MeterProvider.
+// The semantic conventions followed are read from the OTEL_SEMCONV_STABILITY_OPT_IN environment variable
+// When an instrument cannot be created the error is returned along with a recorder not recording on it
+func NewRecorder(provider metric.MeterProvider, metricsPrefix string) (Recorder, error) {
+	return newOtelRecorder(provider, metricsPrefix, defaultConfig())
+}
+
+func newOtelRecorder(provider metric.MeterProvider, metricsPrefix string, cfg *config) (Recorder, error) {
 	metricName := func(metricName string) string {
 		if len(metricsPrefix) > 0 {
-			return metricsPrefix + "." + metricName
+			return metricsPrefix + ".http.server." + metricName
 		}
-		return metricName
+		return "http.server." + metricName
+	}
+	semconvMode := cfg.semconvMode
+	i := &instruments{meter: provider.Meter(instrumentationName, metric.WithInstrumentationVersion(SemVersion()))}
+	r := &otelRecorder{semconvMode: semconvMode}
+	if semconvMode.emitOld() {
+		r.attemptsCounter = i.int64UpDownCounter(metricName("request_count"), metric.WithDescription("Number of Requests"), metric.WithUnit("Count"))
+		r.totalDuration = i.int64Histogram(metricName("duration"), int64HistogramOptions(durationBoundaries(cfg.durationBuckets, time.Millisecond), metric.WithDescription("Time Taken by request"), metric.WithUnit("Milliseconds"))...)
+		r.activeRequestsCounter = i.int64UpDownCounter(metricName("active_requests"), metric.WithDescription("Number of requests inflight"), metric.WithUnit("Count"))
+		r.requestSize = i.int64Histogram(metricName("request_content_length"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Request Size"), metric.WithUnit("Bytes"))...)
+		r.responseSize = i.int64Histogram(metricName("response_content_length"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Response Size"), metric.WithUnit("Bytes"))...)
+		r.requestHeaderSize = i.int64Histogram(metricName("request_header_length"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Request Header Size"), metric.WithUnit("Bytes"))...)
+		r.responseHeaderSize = i.int64Histogram(metricName("response_header_length"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Response Header Size"), metric.WithUnit("Bytes"))...)
 	}
-	meter := otel.Meter(instrumentationName, metric.WithInstrumentationVersion(SemVersion()))
-	attemptsCounter, _ := meter.Int64UpDownCounter(metricName("http.server.request_count"), metric.WithDescription("Number of Requests"), metric.WithUnit("Count"))
-	totalDuration, _ := meter.Int64Histogram(metricName("http.server.duration"), metric.WithDescription("Time Taken by request"), metric.WithUnit("Milliseconds"))
-	activeRequestsCounter, _ := meter.Int64UpDownCounter(metricName("http.server.active_requests"), metric.WithDescription("Number of requests inflight"), metric.WithUnit("Count"))
-	requestSize, _ := meter.Int64Histogram(metricName("http.server.request_content_length"), metric.WithDescription("Request Size"), metric.WithUnit("Bytes"))
-	responseSize, _ := meter.Int64Histogram(metricName("http.server.response_content_length"), metric.WithDescription("Response Size"), metric.WithUnit("Bytes"))
-	return &otelRecorder{
-		attemptsCounter:       attemptsCounter,
-		totalDuration:         totalDuration,
-		activeRequestsCounter: activeRequestsCounter,
-		requestSize:           requestSize,
-		responseSize:          responseSize,
+	if semconvMode == SemconvStable {
+		r.activeRequestsCounter = i.int64UpDownCounter(metricName("active_requests"), metric.WithDescription("Number of active HTTP requests"), metric.WithUnit("{request}"))
+	}
+	if semconvMode.emitStable() {
+		durationBuckets := cfg.durationBuckets
+		if len(durationBuckets) == 0 {
+			durationBuckets = stableDurationBuckets
+		}
+		r.requestDuration = i.float64Histogram(metricName("request.duration"), float64HistogramOptions(durationBoundaries(durationBuckets, time.Second), metric.WithDescription("Duration of HTTP requests"), metric.WithUnit("s"))...)
+		r.requestBodySize = i.int64Histogram(metricName("request.body.size"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Size of HTTP request bodies"), metric.WithUnit("By"))...)
+		r.responseBodySize = i.int64Histogram(metricName("response.body.size"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Size of HTTP response bodies"), metric.WithUnit("By"))...)
+		r.stableRequestHeaderSize = i.int64Histogram(metricName("request.header.size"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Size of HTTP request headers"), metric.WithUnit("By"))...)
+		r.stableResponseHeaderSize = i.int64Histogram(metricName("response.header.size"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Size of HTTP response headers"), metric.WithUnit("By"))...)
 	}
+	r.panicsCounter = i.int64Counter(metricName("panics"), metric.WithDescription("Number of requests whose handler panicked"), metric.WithUnit("{panic}"))
+	r.handlerErrorsCounter = i.int64Counter(metricName("handler_errors"), metric.WithDescription("Number of errors reported by the handlers"), metric.WithUnit("{error}"))
+	return r, i.err
+}
+
+// oldAttributes returns the attributes of the old metrics, the stable ones are left out when both are emitted.
+func (r *otelRecorder) oldAttributes(attributes []attribute.KeyValue) metric.MeasurementOption {
+	if r.semconvMode == SemconvDuplicate {
+		attributes = withoutKeys(attributes, stableAttributeKeys)
+	}
+	return metric.WithAttributes(attributes...)
+}
+
+// stableAttributes returns the attributes of the stable metrics, the old ones are left out when both are emitted.
+func (r *otelRecorder) stableAttributes(attributes []attribute.KeyValue) metric.MeasurementOption {
+	if r.semconvMode == SemconvDuplicate {
+		attributes = withoutKeys(attributes, oldAttributeKeys)
+	}
+	return metric.WithAttributes(attributes...)
 }
 
 // AddRequests increments the number of requests being processed.
+// The stable conventions have no request counter, the count of the duration histogram is used instead.
 func (r *otelRecorder) AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
-	r.attemptsCounter.Add(ctx, quantity, metric.WithAttributes(attributes...))
+	if r.semconvMode.emitOld() {
+		r.attemptsCounter.Add(ctx, quantity, r.oldAttributes(attributes))
+	}
 }
 
 // ObserveHTTPRequestDuration measures the duration of an HTTP request.
 func (r *otelRecorder) ObserveHTTPRequestDuration(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
-	r.totalDuration.Record(ctx, int64(duration/time.Millisecond), metric.WithAttributes(attributes...))
+	if r.semconvMode.emitOld() {
+		r.totalDuration.Record(ctx, int64(duration/time.Millisecond), r.oldAttributes(attributes))
+	}
+	if r.semconvMode.emitStable() {
+		r.requestDuration.Record(ctx, duration.Seconds(), r.stableAttributes(attributes))
+	}
 }
 
-// ObserveHTTPRequestSize measures the size of an HTTP request in bytes.
+// ObserveHTTPRequestSize measures the approximate size of an HTTP request in bytes.
+// The stable conventions measure the size of the body only, recorded by ObserveHTTPRequestBodySize.
 func (r *otelRecorder) ObserveHTTPRequestSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
-	r.requestSize.Record(ctx, sizeBytes, metric.WithAttributes(attributes...))
+	if r.semconvMode.emitOld() {
+		r.requestSize.Record(ctx, sizeBytes, r.oldAttributes(attributes))
+	}
+}
+
+// ObserveHTTPRequestBodySize measures the size of the body of an HTTP request in bytes.
+func (r *otelRecorder) ObserveHTTPRequestBodySize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	if r.semconvMode.emitStable() {
+		r.requestBodySize.Record(ctx, sizeBytes, r.stableAttributes(attributes))
+	}
 }
 
 // ObserveHTTPResponseSize measures the size of an HTTP response in bytes.
 func (r *otelRecorder) ObserveHTTPResponseSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
-	r.responseSize.Record(ctx, sizeBytes, metric.WithAttributes(attributes...))
+	if r.semconvMode.emitOld() {
+		r.responseSize.Record(ctx, sizeBytes, r.oldAttributes(attributes))
+	}
+	if r.semconvMode.emitStable() {
+		r.responseBodySize.Record(ctx, sizeBytes, r.stableAttributes(attributes))
+	}
+}
+
+// ObserveHTTPRequestHeaderSize measures the size of the headers of an HTTP request in bytes.
+func (r *otelRecorder) ObserveHTTPRequestHeaderSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	if r.semconvMode.emitOld() {
+		r.requestHeaderSize.Record(ctx, sizeBytes, r.oldAttributes(attributes))
+	}
+	if r.semconvMode.emitStable() {
+		r.stableRequestHeaderSize.Record(ctx, sizeBytes, r.stableAttributes(attributes))
+	}
+}
+
+// ObserveHTTPResponseHeaderSize measures the size of the headers of an HTTP response in bytes.
+func (r *otelRecorder) ObserveHTTPResponseHeaderSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	if r.semconvMode.emitOld() {
+		r.responseHeaderSize.Record(ctx, sizeBytes, r.oldAttributes(attributes))
+	}
+	if r.semconvMode.emitStable() {
+		r.stableResponseHeaderSize.Record(ctx, sizeBytes, r.stableAttributes(attributes))
+	}
+}
+
+// AddPanics increments the number of requests whose handler panicked.
+func (r *otelRecorder) AddPanics(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	r.panicsCounter.Add(ctx, quantity, metric.WithAttributes(attributes...))
+}
+
+// AddHandlerErrors increments the number of errors reported by the handlers.
+func (r *otelRecorder) AddHandlerErrors(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	r.handlerErrorsCounter.Add(ctx, quantity, metric.WithAttributes(attributes...))
 }
 
 // AddInflightRequests increments and decrements the number of inflight request being processed.
+// The metric has the same name in both conventions, so it carries both attribute sets when both are emitted.
 func (r *otelRecorder) AddInflightRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
 	r.activeRequestsCounter.Add(ctx, quantity, metric.WithAttributes(attributes...))
 }
@@ -14,7 +14,7 @@ type Recorder interface {
 	// ObserveHTTPRequestDuration measures the duration of an HTTP request.
 	ObserveHTTPRequestDuration(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue)
 
-	// ObserveHTTPRequestSize measures the size of an HTTP request in bytes.
+	// ObserveHTTPRequestSize measures the approximate size of an HTTP request in bytes.
 	ObserveHTTPRequestSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue)
 
 	// ObserveHTTPResponseSize measures the size of an HTTP response in bytes.
@@ -23,3 +23,84 @@ type Recorder interface {
 	// AddInflightRequests increments and decrements the number of inflight request being processed.
 	AddInflightRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue)
 }
+
+// RequestBodySizeRecorder is implemented by recorders which measure the size of the request body apart from
+// the approximate size of the request passed to ObserveHTTPRequestSize, which counts the method, the path and the headers.
+// The middleware calls it when the recorder passed using WithRecorder implements it.
+type RequestBodySizeRecorder interface {
+	// ObserveHTTPRequestBodySize measures the size of the body of an HTTP request in bytes.
+	ObserveHTTPRequestBodySize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue)
+}
+
+// HeaderSizeRecorder is implemented by recorders which measure the size of the headers separately
+// from the size of the bodies. The middleware calls it when WithRecordHeaderSize is used.
+type HeaderSizeRecorder interface {
+	// ObserveHTTPRequestHeaderSize measures the size of the headers of an HTTP request in bytes.
+	ObserveHTTPRequestHeaderSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue)
+
+	// ObserveHTTPResponseHeaderSize measures the size of the headers of an HTTP response in bytes.
+	ObserveHTTPResponseHeaderSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue)
+}
+
+// PanicRecorder is implemented by recorders which count the requests whose handler panicked.
+// The middleware calls it when WithRecordPanics is used.
+type PanicRecorder interface {
+	// AddPanics increments the number of requests whose handler panicked.
+	AddPanics(ctx context.Context, quantity int64, attributes []attribute.KeyValue)
+}
+
+// HandlerErrorRecorder is implemented by recorders which count the errors added to the gin context by the handlers.
+// The middleware calls it for each error of the request when the recorder implements it.
+type HandlerErrorRecorder interface {
+	// AddHandlerErrors increments the number of errors reported by the handlers.
+	AddHandlerErrors(ctx context.Context, quantity int64, attributes []attribute.KeyValue)
+}
+
+// RequestSummary summarizes a request once it completed, see SummaryRecorder
+type RequestSummary struct {
+	// Route is the route of the request, nonconfigured when it matched none
+	Route  string
+	Method string
+	// Status is the status code of the response, 500 when the handler panicked
+	Status int
+	// StartTime is the time the request started at and Duration the time it took
+	StartTime time.Time
+	Duration  time.Duration
+	// RequestSize and ResponseSize are the sizes of the bodies in bytes, zero when WithRecordSizeDisabled is used
+	RequestSize  int64
+	ResponseSize int64
+	// Error is the panic of the handler, or the errors the handlers added to the gin context, nil when there is none
+	Error error
+	// Attributes are the attributes recorded with the request by the methods of Recorder, not to be modified
+	Attributes []attribute.KeyValue
+}
+
+// SummaryRecorder is implemented by recorders which record each request at once from its summary,
+// e.g. to compute an Apdex score or to write a log line. The middleware calls it once per request,
+// in addition to the methods of Recorder. RecorderFromSummary adapts a SummaryRecorder to a Recorder.
+type SummaryRecorder interface {
+	// RecordRequest records a request once it completed.
+	RecordRequest(ctx context.Context, summary RequestSummary)
+}
+
+// wrapper is implemented by the recorders forwarding the measurements to other recorders: MultiRecorder,
+// Backend and the decorators. They implement all the optional recorder interfaces, and forward the measurements
+// to the recorders implementing them.
+type wrapper interface {
+	// wraps reports whether implements is true for one of the recorders the measurements are forwarded to.
+	wraps(implements func(Recorder) bool) bool
+}
+
+// optional returns the recorder as the optional interface T, reporting whether it implements it.
+// A wrapper implements T only when one of the recorders it forwards to does, so that the middleware
+// does not measure for nothing, e.g. the size of the headers which no recorder records.
+func optional[T any](recorder Recorder) (T, bool) {
+	t, ok := recorder.(T)
+	if w, isWrapper := recorder.(wrapper); ok && isWrapper {
+		ok = w.wraps(func(r Recorder) bool {
+			_, ok := optional[T](r)
+			return ok
+		})
+	}
+	return t, ok
+}
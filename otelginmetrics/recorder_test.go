@@ -0,0 +1,51 @@
+package otelginmetrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// plainRecorder implements none of the optional recorder interfaces
+type plainRecorder struct{}
+
+func (plainRecorder) AddRequests(context.Context, int64, []attribute.KeyValue) {}
+func (plainRecorder) ObserveHTTPRequestDuration(context.Context, time.Duration, []attribute.KeyValue) {
+}
+func (plainRecorder) ObserveHTTPRequestSize(context.Context, int64, []attribute.KeyValue)  {}
+func (plainRecorder) ObserveHTTPResponseSize(context.Context, int64, []attribute.KeyValue) {}
+func (plainRecorder) AddInflightRequests(context.Context, int64, []attribute.KeyValue)     {}
+
+// headerRecorder implements HeaderSizeRecorder only
+type headerRecorder struct {
+	plainRecorder
+}
+
+func (headerRecorder) ObserveHTTPRequestHeaderSize(context.Context, int64, []attribute.KeyValue)  {}
+func (headerRecorder) ObserveHTTPResponseHeaderSize(context.Context, int64, []attribute.KeyValue) {}
+
+func TestOptional(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		recorder Recorder
+		header   bool
+	}{
+		{"plain", plainRecorder{}, false},
+		{"implementing", headerRecorder{}, true},
+		{"multi", MultiRecorder(plainRecorder{}, Backend(plainRecorder{})), false},
+		{"multi implementing", MultiRecorder(plainRecorder{}, Backend(headerRecorder{})), true},
+		{"decorator", FilterKeys(plainRecorder{}, "http.host"), false},
+		{"decorator implementing", FilterKeys(headerRecorder{}, "http.host"), true},
+		{"stacked", MultiRecorder(plainRecorder{}, AddStaticAttributes(FilterKeys(plainRecorder{}, "http.host"))), false},
+		{"stacked implementing", MultiRecorder(plainRecorder{}, AddStaticAttributes(FilterKeys(headerRecorder{}, "http.host"))), true},
+	} {
+		if _, ok := optional[HeaderSizeRecorder](tt.recorder); ok != tt.header {
+			t.Errorf("%s: got HeaderSizeRecorder %v, want %v", tt.name, ok, tt.header)
+		}
+		if _, ok := optional[SummaryRecorder](tt.recorder); ok {
+			t.Errorf("%s: got SummaryRecorder, want none", tt.name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package otelginmetrics_test
+
+import (
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelginmetrics/otelginmetricstest"
+	"github.com/technologize/otel-go-contrib/recordertest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRecorderConformance(t *testing.T) {
+	newOtelRecorder := func() otelginmetrics.Recorder {
+		recorder, err := otelginmetrics.NewRecorder(otelginmetricstest.NewReader().MeterProvider(), "")
+		if err != nil {
+			// called from the goroutines of the tests of the suite, where t.Fatal must not be used
+			panic(err)
+		}
+		return recorder
+	}
+
+	recorders := map[string]func() otelginmetrics.Recorder{
+		"NewRecorder": newOtelRecorder,
+		"otelginmetricstest": func() otelginmetrics.Recorder {
+			return otelginmetricstest.NewRecorder()
+		},
+		"MultiRecorder": func() otelginmetrics.Recorder {
+			recorder := otelginmetrics.MultiRecorder(
+				newOtelRecorder(),
+				otelginmetricstest.NewRecorder(),
+				otelginmetrics.Backend(otelginmetricstest.NewRecorder(),
+					otelginmetrics.WithBackendAttributeFilter(attribute.NewDenyKeysFilter("http.method")),
+					otelginmetrics.WithBackendQueue(1024),
+				),
+			)
+			t.Cleanup(func() { recorder.Close() })
+			return recorder
+		},
+		"FilterKeys": func() otelginmetrics.Recorder {
+			return otelginmetrics.FilterKeys(otelginmetricstest.NewRecorder(), "http.method")
+		},
+		"RenameKeys": func() otelginmetrics.Recorder {
+			return otelginmetrics.RenameKeys(otelginmetricstest.NewRecorder(), map[attribute.Key]attribute.Key{"http.target": "url.path"})
+		},
+		"AddStaticAttributes": func() otelginmetrics.Recorder {
+			return otelginmetrics.AddStaticAttributes(otelginmetricstest.NewRecorder(), attribute.String("deployment.environment", "test"))
+		},
+		"MapValues": func() otelginmetrics.Recorder {
+			return otelginmetrics.MapValues(otelginmetricstest.NewRecorder(), "http.method", func(value attribute.Value) attribute.Value {
+				return attribute.StringValue("_OTHER")
+			})
+		},
+	}
+	for name, newRecorder := range recorders {
+		newRecorder := newRecorder
+		t.Run(name, func(t *testing.T) {
+			recordertest.Run(t, func() recordertest.Recorder { return newRecorder() })
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package otelginmetrics
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconvstable "go.opentelemetry.io/otel/semconv/v1.21.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// SemconvMode determines which HTTP semantic conventions the metric names and attributes follow
+type SemconvMode int
+
+const (
+	// SemconvOld emits the metrics and attributes used before the HTTP semantic conventions were stable.
+	SemconvOld SemconvMode = iota
+	// SemconvStable emits only the stable metrics and attributes.
+	SemconvStable
+	// SemconvDuplicate emits both the old and the stable metrics and attributes, to migrate gradually.
+	SemconvDuplicate
+)
+
+// SemconvStabilityOptInEnv is the environment variable read by SemconvModeFromEnv.
+// "http" selects SemconvStable and "http/dup" selects SemconvDuplicate
+const SemconvStabilityOptInEnv = "OTEL_SEMCONV_STABILITY_OPT_IN"
+
+// SemconvModeFromEnv returns the SemconvMode selected by the OTEL_SEMCONV_STABILITY_OPT_IN
+// environment variable. SemconvOld is returned when it is not set.
+func SemconvModeFromEnv() SemconvMode {
+	mode := SemconvOld
+	for _, value := range strings.Split(os.Getenv(SemconvStabilityOptInEnv), ",") {
+		switch strings.TrimSpace(value) {
+		case "http/dup":
+			return SemconvDuplicate
+		case "http":
+			mode = SemconvStable
+		}
+	}
+	return mode
+}
+
+func (mode SemconvMode) emitOld() bool {
+	return mode != SemconvStable
+}
+
+func (mode SemconvMode) emitStable() bool {
+	return mode != SemconvOld
+}
+
+// oldAttributeKeys are left out of the stable metrics when both conventions are emitted.
+var oldAttributeKeys = map[attribute.Key]bool{
+	semconv.HTTPMethodKey:     true,
+	semconv.HTTPStatusCodeKey: true,
+	semconv.HTTPHostKey:       true,
+	semconv.HTTPTargetKey:     true,
+	semconv.HTTPSchemeKey:     true,
+	semconv.HTTPURLKey:        true,
+	semconv.HTTPFlavorKey:     true,
+	semconv.HTTPServerNameKey: true,
+}
+
+// stableAttributeKeys are left out of the old metrics when both conventions are emitted.
+var stableAttributeKeys = map[attribute.Key]bool{
+	semconvstable.HTTPRequestMethodKey:      true,
+	semconvstable.HTTPResponseStatusCodeKey: true,
+	semconvstable.URLSchemeKey:              true,
+	semconvstable.ServerAddressKey:          true,
+	semconvstable.ServerPortKey:             true,
+}
+
+func withoutKeys(attributes []attribute.KeyValue, keys map[attribute.Key]bool) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(attributes))
+	for _, attr := range attributes {
+		if !keys[attr.Key] {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// StableAttributes are the default attributes following the stable HTTP semantic conventions
+var StableAttributes = func(serverName, route string, request *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconvstable.HTTPRequestMethodKey.String(stableMethod(request.Method)),
+	}
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+	attrs = append(attrs, semconvstable.URLSchemeKey.String(scheme))
+
+	if serverName != "" {
+		attrs = append(attrs, semconvstable.ServerAddressKey.String(serverName))
+	}
+	if route != "" {
+		attrs = append(attrs, semconvstable.HTTPRouteKey.String(route))
+	}
+	return attrs
+}
+
+// stableMethod returns _OTHER for methods not known to the semantic conventions,
+// so that arbitrary methods sent by clients do not create new time series
+func stableMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "_OTHER"
+}
+
+// attributesForMode returns the attributes func to be used when none is configured
+func attributesForMode(mode SemconvMode) func(serverName, route string, request *http.Request) []attribute.KeyValue {
+	switch mode {
+	case SemconvStable:
+		return StableAttributes
+	case SemconvDuplicate:
+		return func(serverName, route string, request *http.Request) []attribute.KeyValue {
+			attrs := DefaultAttributes(serverName, route, request)
+			for _, attr := range StableAttributes(serverName, route, request) {
+				// http.route is shared by both conventions
+				if attr.Key != semconvstable.HTTPRouteKey {
+					attrs = append(attrs, attr)
+				}
+			}
+			return attrs
+		}
+	}
+	return DefaultAttributes
+}
+
+// statusCodeAttributes returns the status code attributes of a response.
+// Only the old attribute is grouped, the stable conventions require the exact status code
+func (cfg *config) statusCodeAttributes(code int) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if cfg.semconvMode.emitOld() {
+		if cfg.groupedStatus {
+			attrs = append(attrs, semconv.HTTPStatusCodeKey.Int(code/100*100))
+		} else {
+			attrs = append(attrs, semconv.HTTPAttributesFromHTTPStatusCode(code)...)
+		}
+	}
+	if cfg.semconvMode.emitStable() {
+		attrs = append(attrs, semconvstable.HTTPResponseStatusCodeKey.Int(code))
+	}
+	return attrs
+}
+
+// spanStatusCodeAttributes returns the status code attributes of the span of a response,
+// which are never grouped as spans do not create time series
+func (cfg *config) spanStatusCodeAttributes(code int) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if cfg.semconvMode.emitOld() {
+		attrs = append(attrs, semconv.HTTPStatusCodeKey.Int(code))
+	}
+	if cfg.semconvMode.emitStable() {
+		attrs = append(attrs, semconvstable.HTTPResponseStatusCodeKey.Int(code))
+	}
+	return attrs
+}
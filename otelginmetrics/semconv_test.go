@@ -0,0 +1,54 @@
+package otelginmetrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelginmetrics/otelginmetricstest"
+	semconvstable "go.opentelemetry.io/otel/semconv/v1.21.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+func TestMiddlewareDuplicateSemconvStatus(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	reader := otelginmetricstest.NewReader()
+	router := gin.New()
+	router.Use(otelginmetrics.Middleware("test", otelginmetrics.WithMeterProvider(reader.MeterProvider()), otelginmetrics.WithSemconvMode(otelginmetrics.SemconvDuplicate)))
+	router.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusMethodNotAllowed)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if count, _ := reader.Histogram(t, "http.server.request.duration", semconvstable.HTTPResponseStatusCode(http.StatusMethodNotAllowed)); count != 1 {
+		t.Errorf("stable duration: got %d data points with the exact status code, want 1", count)
+	}
+	if count, _ := reader.Histogram(t, "http.server.duration", semconv.HTTPStatusCodeKey.Int(400)); count != 1 {
+		t.Errorf("old duration: got %d data points with the grouped status code, want 1", count)
+	}
+}
+
+func TestMiddlewareDuplicateSemconvRequestSize(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	reader := otelginmetricstest.NewReader()
+	router := gin.New()
+	router.Use(otelginmetrics.Middleware("test", otelginmetrics.WithMeterProvider(reader.MeterProvider()), otelginmetrics.WithSemconvMode(otelginmetrics.SemconvDuplicate)))
+	router.POST("/", func(c *gin.Context) {
+		_, _ = io.ReadAll(c.Request.Body)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("abc")))
+
+	// the stable size is the size of the body and the old size approximates the whole request, as in otelhttpmetrics
+	if count, sum := reader.Histogram(t, "http.server.request.body.size"); count != 1 || sum != 3 {
+		t.Errorf("request body size: got %d data points summing to %v, want 1 summing to 3", count, sum)
+	}
+	if count, sum := reader.Histogram(t, "http.server.request_content_length"); count != 1 || sum <= 3 {
+		t.Errorf("request content length: got %d data points summing to %v, want 1 summing to more than 3", count, sum)
+	}
+}
@@ -0,0 +1,71 @@
+package otelginmetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RecorderFromSummary returns a Recorder passing the summaries of the requests to the SummaryRecorder,
+// to be used with WithRecorder. Its other methods do nothing, the requests in flight are not recorded
+func RecorderFromSummary(recorder SummaryRecorder) Recorder {
+	return summaryRecorder{recorder: recorder}
+}
+
+type summaryRecorder struct {
+	recorder SummaryRecorder
+}
+
+func (r summaryRecorder) RecordRequest(ctx context.Context, summary RequestSummary) {
+	r.recorder.RecordRequest(ctx, summary)
+}
+
+func (summaryRecorder) AddRequests(context.Context, int64, []attribute.KeyValue) {}
+
+func (summaryRecorder) ObserveHTTPRequestDuration(context.Context, time.Duration, []attribute.KeyValue) {
+}
+
+func (summaryRecorder) ObserveHTTPRequestSize(context.Context, int64, []attribute.KeyValue) {}
+
+func (summaryRecorder) ObserveHTTPResponseSize(context.Context, int64, []attribute.KeyValue) {}
+
+func (summaryRecorder) AddInflightRequests(context.Context, int64, []attribute.KeyValue) {}
+
+// SummaryFromRecorder returns a SummaryRecorder recording the summaries of the requests using the methods
+// of the Recorder, e.g. to record the requests summarized by other code with an existing Recorder
+func SummaryFromRecorder(recorder Recorder) SummaryRecorder {
+	return recorderSummary{recorder: recorder}
+}
+
+type recorderSummary struct {
+	recorder Recorder
+}
+
+func (r recorderSummary) RecordRequest(ctx context.Context, summary RequestSummary) {
+	r.recorder.AddRequests(ctx, 1, summary.Attributes)
+	r.recorder.ObserveHTTPRequestSize(ctx, summary.RequestSize, summary.Attributes)
+	if recorder, ok := optional[RequestBodySizeRecorder](r.recorder); ok {
+		recorder.ObserveHTTPRequestBodySize(ctx, summary.RequestSize, summary.Attributes)
+	}
+	r.recorder.ObserveHTTPResponseSize(ctx, summary.ResponseSize, summary.Attributes)
+	r.recorder.ObserveHTTPRequestDuration(ctx, summary.Duration, summary.Attributes)
+}
+
+// handlerError returns the error of the summary of a request: the panic of the handler, or the errors added to the gin context
+func handlerError(panicked interface{}, ginErrors []*gin.Error) error {
+	if panicked != nil {
+		if err, ok := panicked.(error); ok {
+			return fmt.Errorf("panic: %w", err)
+		}
+		return fmt.Errorf("panic: %v", panicked)
+	}
+	errs := make([]error, 0, len(ginErrors))
+	for _, err := range ginErrors {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,67 @@
+package otelginmetrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelginmetrics/otelginmetricstest"
+)
+
+func TestSummaryRoundTrip(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	clock := otelginmetricstest.NewClock(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC))
+	start := clock.Now()
+	summaries := otelginmetricstest.NewRecorder()
+	router := gin.New()
+	// the summaries are passed to a SummaryRecorder adapted to a Recorder
+	router.Use(otelginmetrics.Middleware("test", otelginmetrics.WithRecorder(otelginmetrics.RecorderFromSummary(summaries)), otelginmetrics.WithClock(clock.Now)))
+	router.POST("/users", func(c *gin.Context) {
+		_, _ = io.Copy(io.Discard, c.Request.Body)
+		clock.Advance(time.Second)
+		_ = c.Error(errors.New("duplicate user"))
+		c.String(http.StatusConflict, "exists")
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("hello")))
+
+	recorded := summaries.Summaries()
+	if len(recorded) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(recorded))
+	}
+	summary := recorded[0]
+	if summary.Route != "/users" || summary.Method != http.MethodPost || summary.Status != http.StatusConflict ||
+		!summary.StartTime.Equal(start) || summary.Duration != time.Second ||
+		summary.RequestSize != 5 || summary.ResponseSize != 6 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if summary.Error == nil || !strings.Contains(summary.Error.Error(), "duplicate user") {
+		t.Errorf("expected the error of the handler in the summary, got %v", summary.Error)
+	}
+	if measurements := summaries.Measurements(); len(measurements) != 0 {
+		t.Errorf("expected only the summary to be recorded, got %v", measurements)
+	}
+
+	// the summary recorded back with a Recorder gives the measurements of the request
+	recorder := otelginmetricstest.NewRecorder()
+	otelginmetrics.SummaryFromRecorder(recorder).RecordRequest(context.Background(), summary)
+	// the status code attribute is grouped, unlike the status of the summary
+	recorder.AssertRequestCount(t, "/users", http.StatusBadRequest, 1)
+	recorder.AssertDurationRecorded(t, "/users", time.Second)
+	for kind, want := range map[string]int64{
+		otelginmetricstest.KindRequestSize:     5,
+		otelginmetricstest.KindRequestBodySize: 5,
+		otelginmetricstest.KindResponseSize:    6,
+	} {
+		if sizes := recorder.Filter(kind, nil); len(sizes) != 1 || sizes[0].Value != want {
+			t.Errorf("%s: got %v, want a single measurement of %d", kind, sizes, want)
+		}
+	}
+}
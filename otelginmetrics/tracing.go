@@ -0,0 +1,76 @@
+package otelginmetrics
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing returns middleware that starts a server span for each incoming request,
+// continuing the trace propagated in the request headers.
+// It accepts the same options as Middleware, so that the attributes and the shouldRecord
+// func are the same for both signals. It is to be used before Middleware, so that the
+// metrics are recorded with the context of the span.
+// The service parameter should describe the name of the (virtual)
+// server handling the request.
+func Tracing(service string, options ...Option) gin.HandlerFunc {
+	cfg := newConfig(options)
+	if cfg.disabled {
+		return func(ginCtx *gin.Context) {
+			ginCtx.Next()
+		}
+	}
+	if cfg.attributes == nil {
+		cfg.attributes = attributesForMode(cfg.semconvMode)
+	}
+	tracer := cfg.tracerProvider.Tracer(instrumentationName, trace.WithInstrumentationVersion(SemVersion()))
+	return func(ginCtx *gin.Context) {
+
+		route := ginCtx.FullPath()
+		spanName := route
+		if len(route) <= 0 {
+			route = "nonconfigured"
+			spanName = ginCtx.Request.Method
+		}
+		ruleAttributes, record := cfg.recordRequest(service, route, ginCtx.Request)
+		if !record {
+			ginCtx.Next()
+			return
+		}
+
+		request := ginCtx.Request
+		ctx := cfg.propagators.Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+		ctx, span := tracer.Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(cfg.requestAttributes(service, route, request, ruleAttributes)...),
+		)
+		defer func() {
+			// a panic going up to a recovery middleware outside of this one is recorded as a failed request,
+			// as Middleware does, and continues once recorded
+			if panicked := recover(); panicked != nil {
+				span.SetAttributes(cfg.spanStatusCodeAttributes(http.StatusInternalServerError)...)
+				span.SetAttributes(ErrorTypeKey.String(ErrorTypePanic))
+				span.RecordError(handlerError(panicked, nil), trace.WithStackTrace(true))
+				span.SetStatus(codes.Error, http.StatusText(http.StatusInternalServerError))
+				span.End()
+				panic(panicked)
+			}
+			span.End()
+		}()
+
+		ginCtx.Request = request.WithContext(ctx)
+		ginCtx.Next()
+
+		status := ginCtx.Writer.Status()
+		span.SetAttributes(cfg.spanStatusCodeAttributes(status)...)
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		for _, err := range ginCtx.Errors {
+			span.RecordError(err.Err)
+		}
+	}
+}
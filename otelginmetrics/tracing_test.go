@@ -0,0 +1,81 @@
+package otelginmetrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+func TestTracingPanic(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	router := gin.New()
+	router.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ interface{}) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.Use(otelginmetrics.Tracing("test", otelginmetrics.WithTracerProvider(provider)))
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rw.Code != http.StatusInternalServerError {
+		t.Errorf("the panic did not reach the recovery middleware, got status %d", rw.Code)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Status.Code != codes.Error {
+		t.Errorf("got span status %v, want %v", span.Status.Code, codes.Error)
+	}
+	attributes := attribute.NewSet(span.Attributes...)
+	if status, _ := attributes.Value(semconv.HTTPStatusCodeKey); status.AsInt64() != http.StatusInternalServerError {
+		t.Errorf("got status code attribute %v, want 500", status.Emit())
+	}
+	if errorType, _ := attributes.Value(otelginmetrics.ErrorTypeKey); errorType.AsString() != otelginmetrics.ErrorTypePanic {
+		t.Errorf("got error.type attribute %q, want %q", errorType.AsString(), otelginmetrics.ErrorTypePanic)
+	}
+	if len(span.Events) != 1 || span.Events[0].Name != "exception" {
+		t.Errorf("got span events %v, want an exception", span.Events)
+	}
+}
+
+func TestTracingGlobalPropagator(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	previous := otel.GetTextMapPropagator()
+	defer otel.SetTextMapPropagator(previous)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	router := gin.New()
+	router.Use(otelginmetrics.Tracing("test", otelginmetrics.WithTracerProvider(provider)))
+	router.GET("/", func(c *gin.Context) {})
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if traceID := spans[0].SpanContext.TraceID().String(); traceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("got trace id %s, want the one of the traceparent header", traceID)
+	}
+}
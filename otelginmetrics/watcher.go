@@ -0,0 +1,25 @@
+package otelginmetrics
+
+import (
+	"time"
+
+	"github.com/technologize/otel-go-contrib/internal/rules"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RulesWatcher holds the rules loaded from a file and reloads them when the file changes.
+// The rules are swapped atomically, the requests being recorded keep the rules they started with.
+// It is set on the middleware using WithRules
+type RulesWatcher = rules.Watcher
+
+// WatchRules loads the rules from the file and checks every interval whether the file changed,
+// to reload them. When the changed file cannot be loaded the current rules stay in effect.
+// The reloads and parse failures are counted using the given MeterProvider, the global one when nil.
+// An error is returned when the interval is not positive or the rules cannot be loaded initially
+func WatchRules(filename string, interval time.Duration, provider metric.MeterProvider) (*RulesWatcher, error) {
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+	return rules.Watch(filename, interval, provider.Meter(instrumentationName, metric.WithInstrumentationVersion(SemVersion())))
+}
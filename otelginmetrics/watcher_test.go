@@ -0,0 +1,23 @@
+package otelginmetrics_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+)
+
+func TestWatchRulesNonPositiveInterval(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(filename, []byte("rules: []\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if watcher, err := otelginmetrics.WatchRules(filename, interval, nil); err == nil {
+			_ = watcher.Close()
+			t.Errorf("interval %v accepted", interval)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package otelhttpmetrics
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// BodyStateKey is the attribute describing how the response body was consumed
+const BodyStateKey = attribute.Key("http.response.body.state")
+
+// The states of a response body recorded with the response size and the time to body close
+const (
+	// BodyStateComplete is recorded when the body was read until EOF and closed
+	BodyStateComplete = "complete"
+	// BodyStatePartial is recorded when the body was closed before being read until EOF
+	BodyStatePartial = "partial"
+	// BodyStateNotClosed is recorded when the body was garbage collected without being closed
+	BodyStateNotClosed = "not_closed"
+)
+
+// requestBody counts the bytes read from the body of a request, by the handler for the incoming requests
+// and by the round tripper for the outgoing ones. It may be read from another goroutine than the one recording.
+type requestBody struct {
+	io.ReadCloser
+	size atomic.Int64
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size.Add(int64(n))
+	return n, err
+}
+
+// responseBody wraps the body of a response to count the bytes read from it
+// and to record once it was closed.
+type responseBody struct {
+	io.ReadCloser
+	ctx          context.Context
+	start        time.Time
+	now          func() time.Time
+	recorder     Recorder
+	bodyRecorder BodyRecorder
+	attributes   []attribute.KeyValue
+	size         atomic.Int64
+	eof          atomic.Bool
+	once         sync.Once
+
+	// summary is recorded once the body was closed, nil when the recorder is not a SummaryRecorder
+	summary         *RequestSummary
+	summaryRecorder SummaryRecorder
+}
+
+func newResponseBody(ctx context.Context, body io.ReadCloser, start time.Time, cfg *config, attributes []attribute.KeyValue, summary *RequestSummary) *responseBody {
+	b := &responseBody{
+		ReadCloser: body,
+		ctx:        ctx,
+		start:      start,
+		now:        cfg.now,
+		attributes: attributes,
+		summary:    summary,
+	}
+	if cfg.recordSize {
+		b.recorder = cfg.recorder
+	}
+	if cfg.recordDuration {
+		b.bodyRecorder, _ = optional[BodyRecorder](cfg.recorder)
+	}
+	if summary != nil {
+		b.summaryRecorder = cfg.recorder.(SummaryRecorder)
+	}
+	runtime.SetFinalizer(b, func(b *responseBody) {
+		b.record(BodyStateNotClosed, false)
+	})
+	return b
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size.Add(int64(n))
+	if err == io.EOF {
+		b.eof.Store(true)
+	}
+	return n, err
+}
+
+func (b *responseBody) Close() error {
+	err := b.ReadCloser.Close()
+	runtime.SetFinalizer(b, nil)
+	state := BodyStatePartial
+	if b.eof.Load() {
+		state = BodyStateComplete
+	}
+	b.record(state, true)
+	return err
+}
+
+func (b *responseBody) record(state string, closed bool) {
+	b.once.Do(func() {
+		attributes := append(b.attributes[:len(b.attributes):len(b.attributes)], BodyStateKey.String(state))
+		if b.recorder != nil {
+			b.recorder.ObserveHTTPResponseSize(b.ctx, b.size.Load(), attributes)
+		}
+		if b.bodyRecorder != nil && closed {
+			b.bodyRecorder.ObserveHTTPTimeToBodyClose(b.ctx, b.now().Sub(b.start), attributes)
+		}
+		if b.summary != nil {
+			if b.recorder != nil {
+				b.summary.ResponseSize = b.size.Load()
+			}
+			b.summary.Attributes = attributes
+			b.summaryRecorder.RecordRequest(b.ctx, *b.summary)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package otelhttpmetrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestTransportTimeToFirstByte(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+	clock := otelhttpmetricstest.NewClock(time.Time{})
+	recorder := otelhttpmetricstest.NewRecorder()
+	// the round trippers further down take a second once the first byte was received
+	base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		res, err := server.Client().Transport.RoundTrip(r)
+		clock.Advance(time.Second)
+		return res, err
+	})
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(base, otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithClock(clock.Now))}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	recorder.AssertDurationRecorded(t, "", time.Second)
+	firstBytes := recorder.Filter(otelhttpmetricstest.KindTimeToFirstByte, nil)
+	if len(firstBytes) != 1 || firstBytes[0].Duration != 0 {
+		t.Errorf("time to first byte: got %v, want a single measurement of 0s", firstBytes)
+	}
+}
+
+func TestTransportBodyState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, strings.Repeat("a", 1024))
+	}))
+	defer server.Close()
+	recorder := otelhttpmetricstest.NewRecorder()
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(server.Client().Transport, otelhttpmetrics.WithRecorder(recorder))}
+	responseSizes := func(state string) []otelhttpmetricstest.Measurement {
+		return recorder.Filter(otelhttpmetricstest.KindResponseSize, func(m otelhttpmetricstest.Measurement) bool {
+			value, _ := m.Attributes.Value(otelhttpmetrics.BodyStateKey)
+			return value.AsString() == state
+		})
+	}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+	if sizes := responseSizes(otelhttpmetrics.BodyStateComplete); len(sizes) != 1 || sizes[0].Value != 1024 {
+		t.Errorf("expected the body read until EOF to be recorded complete with 1024 bytes, got %v", sizes)
+	}
+
+	res, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = res.Body.Read(make([]byte, 10))
+	_ = res.Body.Close()
+	if sizes := responseSizes(otelhttpmetrics.BodyStatePartial); len(sizes) != 1 || sizes[0].Value != 10 {
+		t.Errorf("expected the body closed early to be recorded partial with 10 bytes, got %v", sizes)
+	}
+	if n := len(recorder.Filter(otelhttpmetricstest.KindTimeToBodyClose, nil)); n != 2 {
+		t.Errorf("expected the time to body close of the 2 closed bodies, got %d", n)
+	}
+
+	// the body is dropped without being closed
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	// the finalizer of the body records it once garbage collected
+	for i := 0; i < 50 && len(responseSizes(otelhttpmetrics.BodyStateNotClosed)) == 0; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sizes := responseSizes(otelhttpmetrics.BodyStateNotClosed); len(sizes) != 1 {
+		t.Errorf("expected the body left open to be recorded not_closed, got %v", sizes)
+	}
+	if n := len(recorder.Filter(otelhttpmetricstest.KindTimeToBodyClose, nil)); n != 2 {
+		t.Errorf("expected no time to body close for the body left open, got %d measurements", n)
+	}
+}
@@ -0,0 +1,83 @@
+package otelhttpmetrics
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+var (
+	// LatencySensitiveDurationBuckets are duration buckets for APIs answering within milliseconds
+	LatencySensitiveDurationBuckets = []time.Duration{
+		time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond,
+		20 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond,
+		500 * time.Millisecond, time.Second,
+	}
+
+	// BatchDurationBuckets are duration buckets for batch endpoints taking seconds to minutes
+	BatchDurationBuckets = []time.Duration{
+		100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond, time.Second,
+		2500 * time.Millisecond, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+		time.Minute, 2 * time.Minute, 5 * time.Minute,
+	}
+
+	// LatencySensitiveSizeBuckets are size buckets in bytes for small JSON payloads of APIs
+	LatencySensitiveSizeBuckets = []float64{
+		64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20,
+	}
+
+	// BatchSizeBuckets are size buckets in bytes for uploads and downloads of up to a gigabyte
+	BatchSizeBuckets = []float64{
+		1 << 10, 16 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20, 256 << 20, 1 << 30,
+	}
+
+	// stableDurationBuckets are the buckets advised for the duration by the stable HTTP semantic conventions
+	stableDurationBuckets = []time.Duration{
+		5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+		75 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+		750 * time.Millisecond, time.Second, 2500 * time.Millisecond, 5 * time.Second,
+		7500 * time.Millisecond, 10 * time.Second,
+	}
+)
+
+// durationBoundaries converts the duration buckets to boundaries in the given unit
+func durationBoundaries(buckets []time.Duration, unit time.Duration) []float64 {
+	boundaries := make([]float64, len(buckets))
+	for i, bucket := range buckets {
+		boundaries[i] = float64(bucket) / float64(unit)
+	}
+	return boundaries
+}
+
+// int64HistogramOptions returns the options of a histogram, with the bucket boundaries advised when given
+func int64HistogramOptions(boundaries []float64, options ...metric.Int64HistogramOption) []metric.Int64HistogramOption {
+	if len(boundaries) > 0 {
+		options = append(options, metric.WithExplicitBucketBoundaries(boundaries...))
+	}
+	return options
+}
+
+// float64HistogramOptions returns the options of a histogram, with the bucket boundaries advised when given
+func float64HistogramOptions(boundaries []float64, options ...metric.Float64HistogramOption) []metric.Float64HistogramOption {
+	if len(boundaries) > 0 {
+		options = append(options, metric.WithExplicitBucketBoundaries(boundaries...))
+	}
+	return options
+}
+
+// ExponentialHistogramView returns a view which makes the histograms recorded by this package
+// use a base-2 exponential histogram in place of the explicit bucket boundaries.
+// It is to be registered on the MeterProvider with sdkmetric.WithView
+func ExponentialHistogramView() sdkmetric.View {
+	return sdkmetric.NewView(
+		sdkmetric.Instrument{
+			Kind:  sdkmetric.InstrumentKindHistogram,
+			Scope: instrumentation.Scope{Name: instrumentationName},
+		},
+		sdkmetric.Stream{
+			Aggregation: sdkmetric.AggregationBase2ExponentialHistogram{MaxSize: 160, MaxScale: 20},
+		},
+	)
+}
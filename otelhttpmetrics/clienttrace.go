@@ -0,0 +1,205 @@
+package otelhttpmetrics
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconvstable "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// The phases of an outgoing request recorded when WithClientTrace is used
+const (
+	// PhaseDNS is the time taken to resolve the host name
+	PhaseDNS = "dns"
+	// PhaseConnect is the time taken to establish the TCP connection
+	PhaseConnect = "connect"
+	// PhaseTLS is the time taken by the TLS handshake
+	PhaseTLS = "tls"
+	// PhaseGetConn is the time waited for a connection, either from the pool or a new one
+	PhaseGetConn = "get_conn"
+	// PhaseFirstByte is the time from the request being written until the first response byte, i.e. the server think time
+	PhaseFirstByte = "first_byte"
+)
+
+// ConnectionReusedKey is the attribute telling whether the request was sent on a pooled connection
+const ConnectionReusedKey = attribute.Key("http.connection.reused")
+
+// clientTrace collects the timings of the phases of a request using the hooks of httptrace.
+// The hooks may be called from different goroutines, e.g. when dialing several addresses.
+type clientTrace struct {
+	mu sync.Mutex
+	// starts are the start times of the phases in progress, phases the durations of those which ended
+	starts      map[string]time.Time
+	phases      map[string]time.Duration
+	gotConn     bool
+	reused      bool
+	networkType string
+	// now returns the current time, see WithClock
+	now func() time.Time
+}
+
+func newClientTrace(now func() time.Time) *clientTrace {
+	return &clientTrace{starts: make(map[string]time.Time, 5), phases: make(map[string]time.Duration, 5), now: now}
+}
+
+// withContext returns the context with the hooks of the trace installed,
+// in addition to any httptrace.ClientTrace already in the context.
+func (t *clientTrace) withContext(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.starts[PhaseGetConn] = t.now()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.end(PhaseGetConn)
+			t.gotConn = true
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.networkType = networkType(info.Conn.RemoteAddr())
+			}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.starts[PhaseDNS] = t.now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.end(PhaseDNS)
+		},
+		ConnectStart: func(_, _ string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// the first of several addresses dialed in parallel starts the phase
+			if _, ok := t.starts[PhaseConnect]; !ok {
+				t.starts[PhaseConnect] = t.now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.end(PhaseConnect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.starts[PhaseTLS] = t.now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// failed handshakes are left out, the round trip is recorded with error.type=tls
+			if err == nil {
+				t.end(PhaseTLS)
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.starts[PhaseFirstByte] = t.now()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.end(PhaseFirstByte)
+		},
+	})
+}
+
+// end records the duration of the phase since it started, only the first end of a phase is kept.
+// The caller must hold the lock.
+func (t *clientTrace) end(phase string) {
+	start, ok := t.starts[phase]
+	if !ok {
+		return
+	}
+	if _, ok := t.phases[phase]; !ok {
+		t.phases[phase] = t.now().Sub(start)
+	}
+}
+
+// record records the phases of the request along with the attributes of the connection used.
+func (t *clientTrace) record(ctx context.Context, recorder ClientTraceRecorder, res *http.Response, attributes []attribute.KeyValue) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	attrs := make([]attribute.KeyValue, 0, len(attributes)+3)
+	attrs = append(attrs, attributes...)
+	if t.gotConn {
+		attrs = append(attrs, ConnectionReusedKey.Bool(t.reused))
+	}
+	if res != nil {
+		version := strconv.Itoa(res.ProtoMajor)
+		if res.ProtoMajor < 2 {
+			version += "." + strconv.Itoa(res.ProtoMinor)
+		}
+		attrs = append(attrs, semconvstable.NetworkProtocolVersionKey.String(version))
+	}
+	if t.networkType != "" {
+		attrs = append(attrs, semconvstable.NetworkTypeKey.String(t.networkType))
+	}
+
+	for phase, duration := range t.phases {
+		recorder.ObserveHTTPClientPhase(ctx, phase, duration, attrs)
+	}
+}
+
+// firstByteTrace keeps the time the first byte of the response was received, reported by httptrace,
+// as the round trip may return well after it, e.g. once the round trippers further down are done with the headers
+type firstByteTrace struct {
+	now      func() time.Time
+	mu       sync.Mutex
+	at       time.Time
+	received bool
+}
+
+// withContext returns the context with the hook of the trace installed,
+// in addition to any httptrace.ClientTrace already in the context.
+func (t *firstByteTrace) withContext(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if !t.received {
+				t.at = t.now()
+				t.received = true
+			}
+		},
+	})
+}
+
+// since returns the time from start until the first response byte, false when the round tripper
+// did not report it, e.g. when it does not send the request over the network
+func (t *firstByteTrace) since(start time.Time) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.received {
+		return 0, false
+	}
+	return t.at.Sub(start), true
+}
+
+// networkType returns ipv4 or ipv6 following the IP family of the address
+func networkType(addr net.Addr) string {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return ""
+	}
+	if tcpAddr.IP.To4() != nil {
+		return "ipv4"
+	}
+	return "ipv6"
+}
@@ -0,0 +1,66 @@
+package otelhttpmetrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+)
+
+func TestTransportFailedTLSHandshake(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	recorder := otelhttpmetricstest.NewRecorder()
+	// the default transport does not trust the certificate of the test server
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(&http.Transport{}, otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithClientTrace())}
+
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("the TLS handshake succeeded")
+	}
+
+	if phases := recorder.Filter(otelhttpmetricstest.KindClientPhase, func(m otelhttpmetricstest.Measurement) bool {
+		return m.Name == otelhttpmetrics.PhaseTLS
+	}); len(phases) != 0 {
+		t.Errorf("failed TLS handshake recorded as %v", phases)
+	}
+	if phases := recorder.Filter(otelhttpmetricstest.KindClientPhase, func(m otelhttpmetricstest.Measurement) bool {
+		return m.Name == otelhttpmetrics.PhaseConnect
+	}); len(phases) != 1 {
+		t.Errorf("connect phase: got %v, want a single measurement", phases)
+	}
+}
+
+func TestClientTraceClock(t *testing.T) {
+	// the zero time is a valid start of the phases
+	clock := otelhttpmetricstest.NewClock(time.Time{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clock.Advance(2 * time.Second)
+	}))
+	defer server.Close()
+	recorder := otelhttpmetricstest.NewRecorder()
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(&http.Transport{}, otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithClientTrace(), otelhttpmetrics.WithClock(clock.Now))}
+
+	get(t, client, server.URL)
+
+	phases := make(map[string]time.Duration)
+	for _, m := range recorder.Filter(otelhttpmetricstest.KindClientPhase, nil) {
+		phases[m.Name] = m.Duration
+	}
+	// the clock only advances while the server handles the request
+	want := map[string]time.Duration{
+		otelhttpmetrics.PhaseGetConn:   0,
+		otelhttpmetrics.PhaseConnect:   0,
+		otelhttpmetrics.PhaseFirstByte: 2 * time.Second,
+	}
+	if len(phases) != len(want) {
+		t.Errorf("got phases %v, want %v", phases, want)
+	}
+	for phase, duration := range want {
+		if got, ok := phases[phase]; !ok || got != duration {
+			t.Errorf("%s: got %v, want %v", phase, got, duration)
+		}
+	}
+}
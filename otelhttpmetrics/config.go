@@ -2,19 +2,42 @@ package otelhttpmetrics
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/technologize/otel-go-contrib/internal/rules"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type config struct {
-	recordInFlight bool
-	recordSize     bool
-	recordDuration bool
-	groupedStatus  bool
-	recorder       Recorder
-	attributes     func(*http.Request) []attribute.KeyValue
-	shouldRecord   func(*http.Request) bool
+	recordInFlight  bool
+	recordSize      bool
+	recordDuration  bool
+	groupedStatus   bool
+	recorder        Recorder
+	attributes      func(*http.Request) []attribute.KeyValue
+	shouldRecord    func(*http.Request) bool
+	mux             *http.ServeMux
+	semconvMode     SemconvMode
+	durationBuckets []time.Duration
+	sizeBuckets     []float64
+	meterProvider   metric.MeterProvider
+	clientTrace     bool
+	connectionPool  bool
+	tracing         bool
+	tracerProvider  trace.TracerProvider
+	propagators     propagation.TextMapPropagator
+	peerService     string
+	envConfig       bool
+	disabled        bool
+	metricsPrefix   string
+	excludedPaths   []string
+	rules           RulesProvider
+	now             func() time.Time
 }
 
 func defaultConfig() *config {
@@ -23,13 +46,19 @@ func defaultConfig() *config {
 		recordDuration: true,
 		recordSize:     true,
 		groupedStatus:  true,
-		attributes:     DefaultAttributes,
+		semconvMode:    SemconvModeFromEnv(),
+		meterProvider:  otel.GetMeterProvider(),
+		now:            time.Now,
+		tracerProvider: otel.GetTracerProvider(),
+		propagators:    otel.GetTextMapPropagator(),
 		shouldRecord: func(_ *http.Request) bool {
 			return true
 		},
 	}
 }
 
+// DefaultAttributes is used by the transport as the default attributes.
+// The route set with ContextWithRoute is recorded as http.route in place of the path in http.target
 var DefaultAttributes = func(request *http.Request) []attribute.KeyValue {
 	attrs := []attribute.KeyValue{
 		semconv.HTTPMethodKey.String(request.Method),
@@ -39,8 +68,52 @@ var DefaultAttributes = func(request *http.Request) []attribute.KeyValue {
 	if origin != "" {
 		attrs = append(attrs, semconv.HTTPHostKey.String(origin))
 	}
-	if target != "" {
+	if route := RouteFromContext(request.Context()); route != "" {
+		attrs = append(attrs, semconv.HTTPRouteKey.String(route))
+	} else if target != "" {
 		attrs = append(attrs, semconv.HTTPTargetKey.String(target))
 	}
 	return attrs
 }
+
+// DefaultServerAttributes is used by the handler as the default attributes.
+// The raw path is left out, the handler adds the matched route as http.route instead
+var DefaultServerAttributes = func(request *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPMethodKey.String(request.Method),
+	}
+	if request.Host != "" {
+		attrs = append(attrs, semconv.HTTPHostKey.String(request.Host))
+	}
+	return attrs
+}
+
+// newRecorder returns the open telemetry recorder for the given side using the configured MeterProvider.
+// Errors creating the instruments are reported using otel.Handle
+func (cfg *config) newRecorder(side string) Recorder {
+	recorder, err := newOtelRecorder(cfg.meterProvider, cfg.metricsPrefix, side, cfg)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return recorder
+}
+
+// recordRequest returns whether the request is to be recorded, following the excluded paths, the rules
+// and the shouldRecord func, along with the attributes the rules add to the request
+func (cfg *config) recordRequest(route string, request *http.Request) ([]attribute.KeyValue, bool) {
+	if request.URL != nil {
+		for _, path := range cfg.excludedPaths {
+			if request.URL.Path == path {
+				return nil, false
+			}
+		}
+	}
+	var attrs []attribute.KeyValue
+	if cfg.rules != nil {
+		var record bool
+		if attrs, record = rules.Evaluate(cfg.rules.CurrentRules(), route, request); !record {
+			return nil, false
+		}
+	}
+	return attrs, cfg.shouldRecord(request)
+}
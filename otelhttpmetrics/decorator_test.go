@@ -0,0 +1,88 @@
+package otelhttpmetrics_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestDecorators(t *testing.T) {
+	attributes := []attribute.KeyValue{
+		attribute.String("http.host", "example.com"),
+		attribute.String("http.target", "/users/42"),
+		attribute.String("deployment.environment", "staging"),
+	}
+	for _, tt := range []struct {
+		name     string
+		decorate func(otelhttpmetrics.Recorder) otelhttpmetrics.Recorder
+		want     []attribute.KeyValue
+	}{
+		{
+			name: "FilterKeys",
+			decorate: func(recorder otelhttpmetrics.Recorder) otelhttpmetrics.Recorder {
+				return otelhttpmetrics.FilterKeys(recorder, "http.host", "unknown")
+			},
+			want: []attribute.KeyValue{attributes[1], attributes[2]},
+		},
+		{
+			name: "RenameKeys",
+			decorate: func(recorder otelhttpmetrics.Recorder) otelhttpmetrics.Recorder {
+				return otelhttpmetrics.RenameKeys(recorder, map[attribute.Key]attribute.Key{"http.target": "url.path"})
+			},
+			want: []attribute.KeyValue{attributes[0], attribute.String("url.path", "/users/42"), attributes[2]},
+		},
+		{
+			name: "AddStaticAttributes",
+			decorate: func(recorder otelhttpmetrics.Recorder) otelhttpmetrics.Recorder {
+				return otelhttpmetrics.AddStaticAttributes(recorder, attribute.String("deployment.environment", "prod"), attribute.String("service.name", "api"))
+			},
+			// the attributes of the measurements take precedence over the static ones
+			want: append([]attribute.KeyValue{attribute.String("service.name", "api")}, attributes...),
+		},
+		{
+			name: "MapValues",
+			decorate: func(recorder otelhttpmetrics.Recorder) otelhttpmetrics.Recorder {
+				return otelhttpmetrics.MapValues(recorder, "http.target", func(attribute.Value) attribute.Value {
+					return attribute.StringValue("/users/:id")
+				})
+			},
+			want: []attribute.KeyValue{attributes[0], attribute.String("http.target", "/users/:id"), attributes[2]},
+		},
+		{
+			name: "stacked",
+			decorate: func(recorder otelhttpmetrics.Recorder) otelhttpmetrics.Recorder {
+				return otelhttpmetrics.FilterKeys(otelhttpmetrics.RenameKeys(recorder, map[attribute.Key]attribute.Key{"http.target": "url.path"}), "http.host")
+			},
+			want: []attribute.KeyValue{attribute.String("url.path", "/users/42"), attributes[2]},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := otelhttpmetricstest.NewRecorder()
+			decorated := tt.decorate(recorder)
+			passed := append([]attribute.KeyValue(nil), attributes...)
+			decorated.AddRequests(context.Background(), 1, passed)
+			// the optional interfaces of the recorder are forwarded as well
+			decorated.(otelhttpmetrics.ClientTraceRecorder).ObserveHTTPClientPhase(context.Background(), otelhttpmetrics.PhaseDNS, time.Millisecond, passed)
+
+			want := attribute.NewSet(tt.want...)
+			for _, m := range recorder.Measurements() {
+				if !m.Attributes.Equals(&want) {
+					t.Errorf("%s: got attributes %v, want %v", m.Kind, m.Attributes.ToSlice(), tt.want)
+				}
+			}
+			if n := len(recorder.Measurements()); n != 2 {
+				t.Errorf("got %d measurements, want 2", n)
+			}
+			for i := range attributes {
+				if passed[i] != attributes[i] {
+					t.Errorf("the attributes passed were modified: got %v, want %v", passed, attributes)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package otelhttpmetrics
+
+import (
+	"time"
+
+	"github.com/technologize/otel-go-contrib/internal/envconfig"
+	"go.opentelemetry.io/otel"
+)
+
+// The environment variables read when WithEnvConfig is used. Booleans are parsed by strconv.ParseBool,
+// lists are separated by commas and durations are parsed by time.ParseDuration.
+// Invalid values are reported using otel.Handle and ignored
+const (
+	// EnvDisabled disables the recording of the requests altogether when true
+	EnvDisabled = "OTEL_HTTP_METRICS_DISABLED"
+	// EnvRecordInFlight determines whether to record in flight requests
+	EnvRecordInFlight = "OTEL_HTTP_METRICS_RECORD_IN_FLIGHT"
+	// EnvRecordSize determines whether to record the size of requests and responses
+	EnvRecordSize = "OTEL_HTTP_METRICS_RECORD_SIZE"
+	// EnvRecordDuration determines whether to record the duration of requests
+	EnvRecordDuration = "OTEL_HTTP_METRICS_RECORD_DURATION"
+	// EnvGroupedStatus determines whether to group the response status codes
+	EnvGroupedStatus = "OTEL_HTTP_METRICS_GROUPED_STATUS"
+	// EnvMetricsPrefix is the prefix of the metric names, see WithMetricsPrefix
+	EnvMetricsPrefix = "OTEL_HTTP_METRICS_PREFIX"
+	// EnvDurationBuckets are the duration buckets, e.g. "5ms,10ms,100ms,1s"
+	EnvDurationBuckets = "OTEL_HTTP_METRICS_DURATION_BUCKETS"
+	// EnvSizeBuckets are the size buckets in bytes, e.g. "1024,65536,1048576"
+	EnvSizeBuckets = "OTEL_HTTP_METRICS_SIZE_BUCKETS"
+	// EnvExcludedPaths are the paths of the requests not to record, e.g. "/healthz,/metrics"
+	EnvExcludedPaths = "OTEL_HTTP_METRICS_EXCLUDED_PATHS"
+	// EnvSemconvMode is the SemconvMode, one of old, stable or dup.
+	// It takes precedence over OTEL_SEMCONV_STABILITY_OPT_IN
+	EnvSemconvMode = "OTEL_HTTP_METRICS_SEMCONV_MODE"
+)
+
+// newConfig returns the config built from the options. When WithEnvConfig is one of them,
+// the environment variables are applied over the defaults and the options are applied over them,
+// so that explicit options take precedence over the environment.
+func newConfig(options []Option) *config {
+	cfg := defaultConfig()
+	for _, option := range options {
+		option.apply(cfg)
+	}
+	if !cfg.envConfig {
+		return cfg
+	}
+	cfg = defaultConfig()
+	if err := cfg.applyEnv(); err != nil {
+		otel.Handle(err)
+	}
+	for _, option := range options {
+		option.apply(cfg)
+	}
+	return cfg
+}
+
+// applyEnv applies the environment variables which are set to the config
+func (cfg *config) applyEnv() error {
+	var lookup envconfig.Lookup
+	lookup.Bool(EnvDisabled, func(b bool) { cfg.disabled = b })
+	lookup.Bool(EnvRecordInFlight, func(b bool) { cfg.recordInFlight = b })
+	lookup.Bool(EnvRecordSize, func(b bool) { cfg.recordSize = b })
+	lookup.Bool(EnvRecordDuration, func(b bool) { cfg.recordDuration = b })
+	lookup.Bool(EnvGroupedStatus, func(b bool) { cfg.groupedStatus = b })
+	lookup.String(EnvMetricsPrefix, func(prefix string) { cfg.metricsPrefix = prefix })
+	lookup.Durations(EnvDurationBuckets, func(buckets []time.Duration) { cfg.durationBuckets = buckets })
+	lookup.Floats(EnvSizeBuckets, func(buckets []float64) { cfg.sizeBuckets = buckets })
+	lookup.List(EnvExcludedPaths, func(paths []string) { cfg.excludedPaths = paths })
+	envconfig.Enum(&lookup, EnvSemconvMode, "semconv mode", map[string]SemconvMode{
+		"old":    SemconvOld,
+		"stable": SemconvStable,
+		"dup":    SemconvDuplicate,
+	}, func(mode SemconvMode) { cfg.semconvMode = mode })
+	return lookup.Err()
+}
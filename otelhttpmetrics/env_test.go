@@ -0,0 +1,115 @@
+package otelhttpmetrics_test
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+	"go.opentelemetry.io/otel"
+)
+
+// serveEnv sends a request of each path through a handler using the options
+func serveEnv(options []otelhttpmetrics.Option, paths ...string) {
+	mux := http.NewServeMux()
+	for _, path := range paths {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {})
+	}
+	handler := otelhttpmetrics.NewHandler(mux, options...)
+	for _, path := range paths {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+}
+
+// captureErrors returns a func returning the errors reported using otel.Handle while the test runs.
+// The handler returned by otel.GetErrorHandler is the global one, which delegates to the handler set
+// and would delegate to itself once set back, so the errors are logged as by default once the test completed
+func captureErrors(t *testing.T) func() []error {
+	var mu sync.Mutex
+	var errs []error
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+	t.Cleanup(func() {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) { log.Print(err) }))
+	})
+	return func() []error {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]error(nil), errs...)
+	}
+}
+
+func TestEnvConfig(t *testing.T) {
+	t.Setenv(otelhttpmetrics.EnvRecordInFlight, "false")
+	t.Setenv(otelhttpmetrics.EnvExcludedPaths, "/healthz, /metrics")
+	t.Setenv(otelhttpmetrics.EnvMetricsPrefix, "myapp")
+
+	recorder := otelhttpmetricstest.NewRecorder()
+	serveEnv([]otelhttpmetrics.Option{otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithEnvConfig()}, "/users", "/healthz")
+	recorder.AssertRequestCount(t, "/users", http.StatusOK, 1)
+	recorder.AssertRequestCount(t, "/healthz", http.StatusOK, 0)
+	if inflight := recorder.Filter(otelhttpmetricstest.KindInflight, nil); len(inflight) != 0 {
+		t.Errorf("expected no request in flight to be recorded, got %v", inflight)
+	}
+
+	reader := otelhttpmetricstest.NewReader()
+	serveEnv([]otelhttpmetrics.Option{otelhttpmetrics.WithMeterProvider(reader.MeterProvider()), otelhttpmetrics.WithEnvConfig()}, "/users")
+	if count, _ := reader.Histogram(t, "myapp.http.server.duration"); count != 1 {
+		t.Errorf("expected the duration to be recorded with the prefix of the environment, got %d data points", count)
+	}
+
+	// the environment is not read without WithEnvConfig
+	recorder = otelhttpmetricstest.NewRecorder()
+	serveEnv([]otelhttpmetrics.Option{otelhttpmetrics.WithRecorder(recorder)}, "/healthz")
+	recorder.AssertRequestCount(t, "/healthz", http.StatusOK, 1)
+}
+
+func TestEnvConfigPrecedence(t *testing.T) {
+	t.Setenv(otelhttpmetrics.EnvRecordInFlight, "true")
+	t.Setenv(otelhttpmetrics.EnvExcludedPaths, "/healthz")
+
+	// the options take precedence over the environment, whatever their order
+	recorder := otelhttpmetricstest.NewRecorder()
+	serveEnv([]otelhttpmetrics.Option{
+		otelhttpmetrics.WithRecordInFlightDisabled(),
+		otelhttpmetrics.WithEnvConfig(),
+		otelhttpmetrics.WithExcludedPaths("/metrics"),
+		otelhttpmetrics.WithRecorder(recorder),
+	}, "/healthz", "/metrics")
+	recorder.AssertRequestCount(t, "/healthz", http.StatusOK, 1)
+	recorder.AssertRequestCount(t, "/metrics", http.StatusOK, 0)
+	if inflight := recorder.Filter(otelhttpmetricstest.KindInflight, nil); len(inflight) != 0 {
+		t.Errorf("expected no request in flight to be recorded, got %v", inflight)
+	}
+}
+
+func TestEnvConfigDisabled(t *testing.T) {
+	t.Setenv(otelhttpmetrics.EnvDisabled, "true")
+	recorder := otelhttpmetricstest.NewRecorder()
+	serveEnv([]otelhttpmetrics.Option{otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithEnvConfig()}, "/users")
+	if measurements := recorder.Measurements(); len(measurements) != 0 {
+		t.Errorf("expected nothing to be recorded, got %v", measurements)
+	}
+}
+
+func TestEnvConfigInvalid(t *testing.T) {
+	reported := captureErrors(t)
+	t.Setenv(otelhttpmetrics.EnvRecordSize, "maybe")
+	t.Setenv(otelhttpmetrics.EnvSemconvMode, "newest")
+
+	// the invalid values are reported and ignored
+	recorder := otelhttpmetricstest.NewRecorder()
+	serveEnv([]otelhttpmetrics.Option{otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithEnvConfig()}, "/users")
+	if sizes := recorder.Filter(otelhttpmetricstest.KindRequestSize, nil); len(sizes) != 1 {
+		t.Errorf("expected the request size to be recorded by default, got %v", sizes)
+	}
+	if errs := reported(); len(errs) != 1 {
+		t.Errorf("expected the invalid values to be reported together, got %v", errs)
+	}
+}
@@ -0,0 +1,77 @@
+package otelhttpmetrics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrorTypeKey is the attribute describing the class of error a request failed with
+const ErrorTypeKey = attribute.Key("error.type")
+
+// The error types recorded for round trips which failed without a response
+const (
+	ErrorTypeTimeout           = "timeout"
+	ErrorTypeCanceled          = "canceled"
+	ErrorTypeDNS               = "dns"
+	ErrorTypeConnectionRefused = "connection_refused"
+	ErrorTypeTLS               = "tls"
+	ErrorTypeEOF               = "eof"
+	ErrorTypeOther             = "other"
+)
+
+// errorType classifies the error of a failed round trip into one of a bounded set of error types
+func errorType(err error) string {
+	if errors.Is(err, context.Canceled) {
+		return ErrorTypeCanceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTypeTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTypeTimeout
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorTypeDNS
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorTypeConnectionRefused
+	}
+	if isTLSError(err) {
+		return ErrorTypeTLS
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorTypeEOF
+	}
+	return ErrorTypeOther
+}
+
+func isTLSError(err error) bool {
+	var (
+		recordHeaderErr     tls.RecordHeaderError
+		verificationErr     *tls.CertificateVerificationError
+		unknownAuthorityErr x509.UnknownAuthorityError
+		hostnameErr         x509.HostnameError
+		invalidErr          x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &recordHeaderErr),
+		errors.As(err, &verificationErr),
+		errors.As(err, &unknownAuthorityErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &invalidErr):
+		return true
+	}
+	// the alerts of the handshake are not exported by crypto/tls, which wraps them in a *net.OpError
+	// whose Op is "remote error" for the alerts received from the peer and "local error" for those sent to it
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "remote error" || opErr.Op == "local error")
+}
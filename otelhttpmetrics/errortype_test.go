@@ -0,0 +1,44 @@
+package otelhttpmetrics
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestErrorType(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"canceled", &url.Error{Op: "Get", URL: "http://example.com", Err: context.Canceled}, ErrorTypeCanceled},
+		{"deadline exceeded", &url.Error{Op: "Get", URL: "http://example.com", Err: context.DeadlineExceeded}, ErrorTypeTimeout},
+		{"net timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, ErrorTypeTimeout},
+		{"dns", &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}}, ErrorTypeDNS},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ErrorTypeConnectionRefused},
+		{"record header", tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, ErrorTypeTLS},
+		{"certificate verification", &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}, ErrorTypeTLS},
+		{"unknown authority", x509.UnknownAuthorityError{}, ErrorTypeTLS},
+		{"hostname", x509.HostnameError{Host: "example.com"}, ErrorTypeTLS},
+		{"invalid certificate", x509.CertificateInvalidError{Reason: x509.Expired}, ErrorTypeTLS},
+		{"alert received", &net.OpError{Op: "remote error", Err: errors.New("tls: protocol version not supported")}, ErrorTypeTLS},
+		{"alert sent", &net.OpError{Op: "local error", Err: errors.New("tls: unexpected message")}, ErrorTypeTLS},
+		{"eof", fmt.Errorf("read response: %w", io.EOF), ErrorTypeEOF},
+		{"unexpected eof", fmt.Errorf("read response: %w", io.ErrUnexpectedEOF), ErrorTypeEOF},
+		{"tls in the message only", errors.New("proxy said: tls: nope"), ErrorTypeOther},
+		{"generic", errors.New("something failed"), ErrorTypeOther},
+	} {
+		if got := errorType(tt.err); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package otelhttpmetrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// contextRecorder keeps the span context of the contexts the requests are recorded with,
+// which the SDK takes the trace and span IDs of the exemplars from
+type contextRecorder struct {
+	*otelhttpmetricstest.Recorder
+	mu    sync.Mutex
+	spans []trace.SpanContext
+}
+
+func (r *contextRecorder) AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	r.mu.Lock()
+	r.spans = append(r.spans, trace.SpanContextFromContext(ctx))
+	r.mu.Unlock()
+	r.Recorder.AddRequests(ctx, quantity, attributes)
+}
+
+func TestTransportExemplarContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	spans := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test")
+	// a tracing round tripper wrapped by the transport starts the client span in the context of the request it sends
+	tracing := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		ctx, span := tracer.Start(r.Context(), "client", trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+		return server.Client().Transport.RoundTrip(r.WithContext(ctx))
+	})
+	recorder := &contextRecorder{Recorder: otelhttpmetricstest.NewRecorder()}
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(tracing, otelhttpmetrics.WithRecorder(recorder))}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	ended := spans.Ended()
+	if len(ended) != 1 || len(recorder.spans) != 1 {
+		t.Fatalf("got %d spans and %d requests recorded, want 1 and 1", len(ended), len(recorder.spans))
+	}
+	if got, want := recorder.spans[0], ended[0].SpanContext(); got.TraceID() != want.TraceID() || got.SpanID() != want.SpanID() {
+		t.Errorf("recorded with the span %s/%s, want %s/%s", got.TraceID(), got.SpanID(), want.TraceID(), want.SpanID())
+	}
+}
@@ -0,0 +1,130 @@
+package otelhttpmetrics
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+type handler struct {
+	next http.Handler
+	cfg  *config
+}
+
+// NewHandler returns a http.Handler that records metrics of the incoming requests
+// served by the given handler.
+// The http.route attribute is taken from the ServeMux pattern matching the request.
+// When h is not a *http.ServeMux, WithServeMux can be used to look the pattern up.
+func NewHandler(h http.Handler, options ...Option) http.Handler {
+	cfg := newConfig(options)
+	if cfg.disabled {
+		return h
+	}
+	if cfg.attributes == nil {
+		cfg.attributes = attributesForMode(cfg.semconvMode, DefaultServerAttributes, StableServerAttributes)
+	}
+	if cfg.recorder == nil {
+		cfg.recorder = cfg.newRecorder("http.server")
+	}
+	if mux, ok := h.(*http.ServeMux); ok && cfg.mux == nil {
+		cfg.mux = mux
+	}
+
+	return &handler{
+		next: h,
+		cfg:  cfg,
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cfg := h.cfg
+	recorder := cfg.recorder
+	route := serverRoute(cfg.mux, r)
+	ruleAttributes, record := cfg.recordRequest(route, r)
+	if !record {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	ctx := withoutCancel(r.Context())
+	start := cfg.now()
+	attributes := cfg.attributes(r)
+	attributes = append(attributes[:len(attributes):len(attributes)], ruleAttributes...)
+	reqAttributes := routeAttributes(attributes, route)
+
+	if cfg.recordInFlight {
+		recorder.AddInflightRequests(ctx, 1, reqAttributes)
+		defer recorder.AddInflightRequests(ctx, -1, reqAttributes)
+	}
+
+	rw, wrapped := newResponseWriter(w)
+
+	var body *requestBody
+	if cfg.recordSize && r.Body != nil {
+		// handlers must not modify the request they are passed, the body is replaced on a shallow copy
+		body = &requestBody{ReadCloser: r.Body}
+		counted := *r
+		counted.Body = body
+		r = &counted
+	}
+
+	defer func() {
+
+		if route == "" {
+			// The pattern is set on the request once a ServeMux further down the chain matched it
+			route = serverRoute(nil, r)
+		}
+		resAttributes := routeAttributes(attributes, route)
+		resAttributes = append(resAttributes, cfg.statusCodeAttributes(rw.Status())...)
+
+		recorder.AddRequests(ctx, 1, resAttributes)
+
+		var requestSize, responseSize int64
+		if cfg.recordSize {
+			if body != nil {
+				requestSize = body.size.Load()
+			}
+			responseSize = rw.Size()
+			recorder.ObserveHTTPRequestSize(ctx, computeApproximateRequestSize(r, requestSize), resAttributes)
+			if bodySizeRecorder, ok := optional[RequestBodySizeRecorder](recorder); ok {
+				bodySizeRecorder.ObserveHTTPRequestBodySize(ctx, requestSize, resAttributes)
+			}
+			recorder.ObserveHTTPResponseSize(ctx, responseSize, resAttributes)
+		}
+
+		duration := cfg.now().Sub(start)
+		if cfg.recordDuration {
+			recorder.ObserveHTTPRequestDuration(ctx, duration, resAttributes)
+		}
+
+		if summaryRecorder, ok := optional[SummaryRecorder](recorder); ok {
+			if route == "" {
+				route = unmatchedRoute
+			}
+			summaryRecorder.RecordRequest(ctx, RequestSummary{
+				Route:        route,
+				Method:       r.Method,
+				Status:       rw.Status(),
+				StartTime:    start,
+				Duration:     duration,
+				RequestSize:  requestSize,
+				ResponseSize: responseSize,
+				Attributes:   resAttributes,
+			})
+		}
+	}()
+
+	h.next.ServeHTTP(wrapped, r)
+}
+
+// routeAttributes returns a copy of attributes with the http.route attribute
+// appended, using the unmatched route when the route is not known.
+func routeAttributes(attributes []attribute.KeyValue, route string) []attribute.KeyValue {
+	if route == "" {
+		route = unmatchedRoute
+	}
+	attrs := make([]attribute.KeyValue, 0, len(attributes)+2)
+	attrs = append(attrs, attributes...)
+	return append(attrs, semconv.HTTPRouteKey.String(route))
+}
@@ -0,0 +1,208 @@
+package otelhttpmetrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+)
+
+func TestHandlerStatusAndSize(t *testing.T) {
+	recorder := otelhttpmetricstest.NewRecorder()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		// the informational status precedes the final one, which is the one recorded
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, "not found")
+	})
+	// the writer of httptest does not handle the informational status codes, a server is used instead
+	server := httptest.NewServer(otelhttpmetrics.NewHandler(mux, otelhttpmetrics.WithRecorder(recorder)))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+	// the server waits for the handler to return, and the request to be recorded
+	server.Close()
+
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("got status %d written to the response, want 404", response.StatusCode)
+	}
+	summaries := recorder.Summaries()
+	if len(summaries) != 1 || summaries[0].Status != http.StatusNotFound || summaries[0].ResponseSize != 9 {
+		t.Fatalf("got summaries %+v, want a 404 of 9 bytes", summaries)
+	}
+	recorder.AssertRequestCount(t, "/users", http.StatusBadRequest, 1)
+	sizes := recorder.Filter(otelhttpmetricstest.KindResponseSize, nil)
+	if len(sizes) != 1 || sizes[0].Value != 9 {
+		t.Errorf("got response sizes %+v, want 9 bytes", sizes)
+	}
+}
+
+func TestHandlerRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/docs/", func(w http.ResponseWriter, r *http.Request) {})
+	for _, tt := range []struct {
+		name string
+		// wrapped hides the mux from the handler, so it is looked up only when passed using WithServeMux
+		wrapped bool
+		path    string
+		route   string
+		status  int
+	}{
+		{
+			name:   "matched",
+			path:   "/users/42",
+			route:  "/users/",
+			status: http.StatusOK,
+		},
+		{
+			name:   "unmatched",
+			path:   "/missing",
+			route:  "nonconfigured",
+			status: http.StatusNotFound,
+		},
+		{
+			// the route is the pattern the request is redirected to
+			name:   "redirect to the trailing slash",
+			path:   "/docs",
+			route:  "/docs/",
+			status: http.StatusMovedPermanently,
+		},
+		{
+			name:    "serve mux",
+			wrapped: true,
+			path:    "/users/42",
+			route:   "/users/",
+			status:  http.StatusOK,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := otelhttpmetricstest.NewRecorder()
+			handler := otelhttpmetrics.NewHandler(mux, otelhttpmetrics.WithRecorder(recorder))
+			if tt.wrapped {
+				handler = otelhttpmetrics.NewHandler(http.HandlerFunc(mux.ServeHTTP), otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithServeMux(mux))
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			summaries := recorder.Summaries()
+			if len(summaries) != 1 || summaries[0].Route != tt.route || summaries[0].Status != tt.status {
+				t.Fatalf("got summaries %+v, want the route %s and the status %d", summaries, tt.route, tt.status)
+			}
+			requests := recorder.Filter(otelhttpmetricstest.KindRequests, nil)
+			if len(requests) != 1 || requests[0].Route() != tt.route {
+				t.Errorf("got requests %+v, want the route %s", requests, tt.route)
+			}
+		})
+	}
+}
+
+func TestHandlerInflight(t *testing.T) {
+	recorder := otelhttpmetricstest.NewRecorder()
+	var inflight int64
+	handler := otelhttpmetrics.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inflight = recorder.Inflight()
+	}), otelhttpmetrics.WithRecorder(recorder))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if inflight != 1 {
+		t.Errorf("got %d requests in flight while handling the request, want 1", inflight)
+	}
+	if inflight := recorder.Inflight(); inflight != 0 {
+		t.Errorf("got %d requests in flight once the request completed, want 0", inflight)
+	}
+}
+
+func TestHandlerPassthrough(t *testing.T) {
+	type capabilities struct {
+		flusher, hijacker, pusher, readerFrom bool
+	}
+	capabilitiesOf := func(w http.ResponseWriter) capabilities {
+		var c capabilities
+		_, c.flusher = w.(http.Flusher)
+		_, c.hijacker = w.(http.Hijacker)
+		_, c.pusher = w.(http.Pusher)
+		_, c.readerFrom = w.(io.ReaderFrom)
+		return c
+	}
+
+	t.Run("recorder", func(t *testing.T) {
+		// the writer of httptest implements http.Flusher only
+		var got capabilities
+		handler := otelhttpmetrics.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = capabilitiesOf(w)
+		}), otelhttpmetrics.WithRecorder(otelhttpmetricstest.NewRecorder()))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if want := (capabilities{flusher: true}); got != want {
+			t.Errorf("got capabilities %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("server", func(t *testing.T) {
+		// the writer of an HTTP/1 server implements all but http.Pusher
+		recorder := otelhttpmetricstest.NewRecorder()
+		var got, want capabilities
+		server := httptest.NewServer(otelhttpmetrics.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = capabilitiesOf(w)
+			want = capabilitiesOf(w.(interface{ Unwrap() http.ResponseWriter }).Unwrap())
+			// io.Copy uses ReadFrom, the bytes copied are counted as written
+			_, _ = io.Copy(w, strings.NewReader("hello"))
+		}), otelhttpmetrics.WithRecorder(recorder)))
+		defer server.Close()
+
+		response, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+		server.Close()
+
+		if got != want || !want.readerFrom || !want.hijacker {
+			t.Errorf("got capabilities %+v, want %+v", got, want)
+		}
+		summaries := recorder.Summaries()
+		if len(summaries) != 1 || summaries[0].ResponseSize != 5 {
+			t.Errorf("got summaries %+v, want a response of 5 bytes", summaries)
+		}
+	})
+
+	t.Run("hijack", func(t *testing.T) {
+		recorder := otelhttpmetricstest.NewRecorder()
+		server := httptest.NewServer(otelhttpmetrics.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, _, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_, _ = io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+			_ = conn.Close()
+		}), otelhttpmetrics.WithRecorder(recorder)))
+		defer server.Close()
+
+		response, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = response.Body.Close()
+		server.Close()
+
+		summaries := recorder.Summaries()
+		if len(summaries) != 1 || summaries[0].Status != http.StatusSwitchingProtocols {
+			t.Errorf("got summaries %+v, want a 101 once the connection was hijacked", summaries)
+		}
+	})
+}
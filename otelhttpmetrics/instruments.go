@@ -0,0 +1,66 @@
+package otelhttpmetrics
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// instruments creates the instruments of a recorder and collects the errors returned.
+// An instrument which could not be created is replaced by a no-op one, so that it is safe to record on it
+type instruments struct {
+	meter metric.Meter
+	err   error
+}
+
+func (i *instruments) add(err error) {
+	if err != nil {
+		i.err = errors.Join(i.err, err)
+	}
+}
+
+func (i *instruments) int64UpDownCounter(name string, options ...metric.Int64UpDownCounterOption) metric.Int64UpDownCounter {
+	counter, err := i.meter.Int64UpDownCounter(name, options...)
+	i.add(err)
+	if counter == nil {
+		return noop.Int64UpDownCounter{}
+	}
+	return counter
+}
+
+func (i *instruments) int64Histogram(name string, options ...metric.Int64HistogramOption) metric.Int64Histogram {
+	histogram, err := i.meter.Int64Histogram(name, options...)
+	i.add(err)
+	if histogram == nil {
+		return noop.Int64Histogram{}
+	}
+	return histogram
+}
+
+func (i *instruments) float64Histogram(name string, options ...metric.Float64HistogramOption) metric.Float64Histogram {
+	histogram, err := i.meter.Float64Histogram(name, options...)
+	i.add(err)
+	if histogram == nil {
+		return noop.Float64Histogram{}
+	}
+	return histogram
+}
+
+func (i *instruments) int64Counter(name string, options ...metric.Int64CounterOption) metric.Int64Counter {
+	counter, err := i.meter.Int64Counter(name, options...)
+	i.add(err)
+	if counter == nil {
+		return noop.Int64Counter{}
+	}
+	return counter
+}
+
+func (i *instruments) int64ObservableUpDownCounter(name string, options ...metric.Int64ObservableUpDownCounterOption) metric.Int64ObservableUpDownCounter {
+	counter, err := i.meter.Int64ObservableUpDownCounter(name, options...)
+	i.add(err)
+	if counter == nil {
+		return noop.Int64ObservableUpDownCounter{}
+	}
+	return counter
+}
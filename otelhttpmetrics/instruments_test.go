@@ -0,0 +1,71 @@
+package otelhttpmetrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+var errInstrument = errors.New("instrument not created")
+
+// failingMeterProvider returns meters failing to create any instrument
+type failingMeterProvider struct{ noop.MeterProvider }
+
+func (failingMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return failingMeter{}
+}
+
+type failingMeter struct{ noop.Meter }
+
+func (failingMeter) Int64Counter(string, ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return nil, errInstrument
+}
+
+func (failingMeter) Int64UpDownCounter(string, ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	return nil, errInstrument
+}
+
+func (failingMeter) Int64Histogram(string, ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return nil, errInstrument
+}
+
+func (failingMeter) Float64Histogram(string, ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return nil, errInstrument
+}
+
+func (failingMeter) Int64ObservableUpDownCounter(string, ...metric.Int64ObservableUpDownCounterOption) (metric.Int64ObservableUpDownCounter, error) {
+	return nil, errInstrument
+}
+
+func TestNewRecorderFailingInstruments(t *testing.T) {
+	// the instruments of the old conventions, the stable ones and both
+	for _, optIn := range []string{"", "http", "http/dup"} {
+		t.Setenv(otelhttpmetrics.SemconvStabilityOptInEnv, optIn)
+		recorder, err := otelhttpmetrics.NewRecorder(failingMeterProvider{}, "")
+		if !errors.Is(err, errInstrument) {
+			t.Fatalf("got error %v, want %v", err, errInstrument)
+		}
+		if recorder == nil {
+			t.Fatal("got no recorder along with the error")
+		}
+
+		// the recorder does not record, but it is safe to use
+		server := httptest.NewServer(otelhttpmetrics.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		}), otelhttpmetrics.WithRecorder(recorder)))
+		client := &http.Client{Transport: otelhttpmetrics.NewTransport(http.DefaultTransport,
+			otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithClientTrace())}
+		response, err := client.Post(server.URL, "text/plain", strings.NewReader("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = response.Body.Close()
+		server.Close()
+	}
+}
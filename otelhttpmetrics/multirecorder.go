@@ -0,0 +1,361 @@
+package otelhttpmetrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RecorderCloser is a Recorder holding resources released by Close, such as the queues of MultiRecorder
+type RecorderCloser interface {
+	Recorder
+	io.Closer
+}
+
+// BackendOption configures a backend of MultiRecorder, see Backend
+type BackendOption interface {
+	applyBackend(b *backend)
+}
+
+type backendOptionFunc func(b *backend)
+
+func (f backendOptionFunc) applyBackend(b *backend) {
+	f(b)
+}
+
+// WithBackendAttributeFilter keeps only the attributes for which the filter returns true
+// in the measurements forwarded to the backend, e.g. to drop high cardinality attributes for one system
+// By default all the attributes are forwarded
+func WithBackendAttributeFilter(filter attribute.Filter) BackendOption {
+	return backendOptionFunc(func(b *backend) {
+		b.filter = filter
+	})
+}
+
+// WithBackendQueue forwards the measurements to the backend asynchronously, through a queue of the given size,
+// so that a slow backend does not slow the requests down. The measurements are dropped while the queue is full,
+// which is reported using otel.Handle. A dropped decrement leaves the in flight requests of the backend too high.
+// The queue is stopped by closing the backend or the MultiRecorder it was passed to
+// By default the measurements are forwarded synchronously
+func WithBackendQueue(size int) BackendOption {
+	return backendOptionFunc(func(b *backend) {
+		b.queueSize = size
+	})
+}
+
+// Backend returns the recorder configured by the options, to be passed to MultiRecorder
+func Backend(recorder Recorder, options ...BackendOption) RecorderCloser {
+	b := &backend{recorder: recorder}
+	for _, option := range options {
+		option.applyBackend(b)
+	}
+	if b.queueSize > 0 {
+		b.queue = make(chan func(), b.queueSize)
+		b.stopped = make(chan struct{})
+		go b.run()
+	}
+	return b
+}
+
+// MultiRecorder returns a recorder forwarding each measurement to all the recorders, e.g. to emit metrics
+// to two systems during a migration. The recorders are isolated from each other and from the request:
+// a panic of a recorder is recovered and reported using otel.Handle.
+// The recorders returned by Backend get their own attribute filter or queue.
+// The optional recorder interfaces are called by the handler and the transport only when one of the recorders implements them.
+// Close stops the queues once the measurements queued were recorded, the following ones are recorded synchronously
+func MultiRecorder(recorders ...Recorder) RecorderCloser {
+	m := &multiRecorder{backends: make([]*backend, 0, len(recorders))}
+	for _, recorder := range recorders {
+		b, ok := recorder.(*backend)
+		if !ok {
+			b = &backend{recorder: recorder}
+		}
+		m.backends = append(m.backends, b)
+	}
+	return m
+}
+
+// backend forwards the measurements to a recorder, recovering its panics
+type backend struct {
+	recorder  Recorder
+	filter    attribute.Filter
+	queueSize int
+	queue     chan func()
+	dropped   atomic.Int64
+
+	// mu guards closed, the queue is closed once no measurement is being queued
+	mu      sync.RWMutex
+	closed  bool
+	stopped chan struct{}
+}
+
+func (b *backend) run() {
+	defer close(b.stopped)
+	for record := range b.queue {
+		b.call(record)
+	}
+}
+
+// do records synchronously, or asynchronously when the backend has a queue which was not closed
+func (b *backend) do(record func()) {
+	if b.queue == nil || !b.enqueue(record) {
+		b.call(record)
+	}
+}
+
+// enqueue queues the record, which is dropped when the queue is full. False is returned once the queue was closed
+func (b *backend) enqueue(record func()) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return false
+	}
+	select {
+	case b.queue <- record:
+	default:
+		// reporting each dropped measurement would flood the error handler while the backend is slow
+		if dropped := b.dropped.Add(1); dropped&(dropped-1) == 0 {
+			otel.Handle(fmt.Errorf("recorder queue full, %d measurements dropped", dropped))
+		}
+	}
+	return true
+}
+
+// Close closes the queue and waits for the measurements queued to be recorded
+func (b *backend) Close() error {
+	if b.queue == nil {
+		return nil
+	}
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.mu.Unlock()
+	<-b.stopped
+	return nil
+}
+
+func (b *backend) call(record func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			otel.Handle(fmt.Errorf("recorder %T panicked: %v", b.recorder, r))
+		}
+	}()
+	record()
+}
+
+// attributes returns the attributes to forward, copied when they are recorded asynchronously
+// as the caller reuses the slice
+func (b *backend) attributes(attributes []attribute.KeyValue) []attribute.KeyValue {
+	if b.filter == nil {
+		if b.queue == nil {
+			return attributes
+		}
+		return append([]attribute.KeyValue(nil), attributes...)
+	}
+	filtered := make([]attribute.KeyValue, 0, len(attributes))
+	for _, attr := range attributes {
+		if b.filter(attr) {
+			filtered = append(filtered, attr)
+		}
+	}
+	return filtered
+}
+
+func (b *backend) wraps(implements func(Recorder) bool) bool {
+	return implements(b.recorder)
+}
+
+func (b *backend) AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	attributes = b.attributes(attributes)
+	b.do(func() { b.recorder.AddRequests(ctx, quantity, attributes) })
+}
+
+func (b *backend) ObserveHTTPRequestDuration(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	attributes = b.attributes(attributes)
+	b.do(func() { b.recorder.ObserveHTTPRequestDuration(ctx, duration, attributes) })
+}
+
+func (b *backend) ObserveHTTPRequestSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	attributes = b.attributes(attributes)
+	b.do(func() { b.recorder.ObserveHTTPRequestSize(ctx, sizeBytes, attributes) })
+}
+
+func (b *backend) ObserveHTTPRequestBodySize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	if recorder, ok := b.recorder.(RequestBodySizeRecorder); ok {
+		attributes = b.attributes(attributes)
+		b.do(func() { recorder.ObserveHTTPRequestBodySize(ctx, sizeBytes, attributes) })
+	}
+}
+
+func (b *backend) ObserveHTTPResponseSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	attributes = b.attributes(attributes)
+	b.do(func() { b.recorder.ObserveHTTPResponseSize(ctx, sizeBytes, attributes) })
+}
+
+func (b *backend) AddInflightRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	attributes = b.attributes(attributes)
+	b.do(func() { b.recorder.AddInflightRequests(ctx, quantity, attributes) })
+}
+
+func (b *backend) ObserveHTTPTimeToFirstByte(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	if recorder, ok := b.recorder.(BodyRecorder); ok {
+		attributes = b.attributes(attributes)
+		b.do(func() { recorder.ObserveHTTPTimeToFirstByte(ctx, duration, attributes) })
+	}
+}
+
+func (b *backend) ObserveHTTPTimeToBodyClose(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	if recorder, ok := b.recorder.(BodyRecorder); ok {
+		attributes = b.attributes(attributes)
+		b.do(func() { recorder.ObserveHTTPTimeToBodyClose(ctx, duration, attributes) })
+	}
+}
+
+func (b *backend) ObserveHTTPClientPhase(ctx context.Context, phase string, duration time.Duration, attributes []attribute.KeyValue) {
+	if recorder, ok := b.recorder.(ClientTraceRecorder); ok {
+		attributes = b.attributes(attributes)
+		b.do(func() { recorder.ObserveHTTPClientPhase(ctx, phase, duration, attributes) })
+	}
+}
+
+func (b *backend) AddConnections(ctx context.Context, event string, quantity int64, attributes []attribute.KeyValue) {
+	if recorder, ok := b.recorder.(ConnectionPoolRecorder); ok {
+		attributes = b.attributes(attributes)
+		b.do(func() { recorder.AddConnections(ctx, event, quantity, attributes) })
+	}
+}
+
+// ObserveConnectionPool registers the func with the backend synchronously, filtering the attributes it reports
+func (b *backend) ObserveConnectionPool(observe func() []ConnectionPoolStats) (err error) {
+	recorder, ok := b.recorder.(ConnectionPoolRecorder)
+	if !ok {
+		return nil
+	}
+	if b.filter != nil {
+		unfiltered := observe
+		observe = func() []ConnectionPoolStats {
+			stats := unfiltered()
+			for i := range stats {
+				stats[i].Attributes = b.attributes(stats[i].Attributes)
+			}
+			return stats
+		}
+	}
+	b.call(func() { err = recorder.ObserveConnectionPool(observe) })
+	return err
+}
+
+func (b *backend) RecordRequest(ctx context.Context, summary RequestSummary) {
+	if recorder, ok := b.recorder.(SummaryRecorder); ok {
+		summary.Attributes = b.attributes(summary.Attributes)
+		b.do(func() { recorder.RecordRequest(ctx, summary) })
+	}
+}
+
+// multiRecorder forwards the measurements to each of its backends
+type multiRecorder struct {
+	backends []*backend
+}
+
+// Close closes the queues of the backends
+func (m *multiRecorder) Close() error {
+	errs := make([]error, 0, len(m.backends))
+	for _, b := range m.backends {
+		errs = append(errs, b.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (m *multiRecorder) wraps(implements func(Recorder) bool) bool {
+	for _, b := range m.backends {
+		if b.wraps(implements) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *multiRecorder) AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	for _, b := range m.backends {
+		b.AddRequests(ctx, quantity, attributes)
+	}
+}
+
+func (m *multiRecorder) ObserveHTTPRequestDuration(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	for _, b := range m.backends {
+		b.ObserveHTTPRequestDuration(ctx, duration, attributes)
+	}
+}
+
+func (m *multiRecorder) ObserveHTTPRequestSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	for _, b := range m.backends {
+		b.ObserveHTTPRequestSize(ctx, sizeBytes, attributes)
+	}
+}
+
+func (m *multiRecorder) ObserveHTTPRequestBodySize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	for _, b := range m.backends {
+		b.ObserveHTTPRequestBodySize(ctx, sizeBytes, attributes)
+	}
+}
+
+func (m *multiRecorder) ObserveHTTPResponseSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	for _, b := range m.backends {
+		b.ObserveHTTPResponseSize(ctx, sizeBytes, attributes)
+	}
+}
+
+func (m *multiRecorder) AddInflightRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	for _, b := range m.backends {
+		b.AddInflightRequests(ctx, quantity, attributes)
+	}
+}
+
+func (m *multiRecorder) ObserveHTTPTimeToFirstByte(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	for _, b := range m.backends {
+		b.ObserveHTTPTimeToFirstByte(ctx, duration, attributes)
+	}
+}
+
+func (m *multiRecorder) ObserveHTTPTimeToBodyClose(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	for _, b := range m.backends {
+		b.ObserveHTTPTimeToBodyClose(ctx, duration, attributes)
+	}
+}
+
+func (m *multiRecorder) ObserveHTTPClientPhase(ctx context.Context, phase string, duration time.Duration, attributes []attribute.KeyValue) {
+	for _, b := range m.backends {
+		b.ObserveHTTPClientPhase(ctx, phase, duration, attributes)
+	}
+}
+
+func (m *multiRecorder) AddConnections(ctx context.Context, event string, quantity int64, attributes []attribute.KeyValue) {
+	for _, b := range m.backends {
+		b.AddConnections(ctx, event, quantity, attributes)
+	}
+}
+
+func (m *multiRecorder) ObserveConnectionPool(observe func() []ConnectionPoolStats) error {
+	var errs []error
+	for _, b := range m.backends {
+		if err := b.ObserveConnectionPool(observe); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *multiRecorder) RecordRequest(ctx context.Context, summary RequestSummary) {
+	for _, b := range m.backends {
+		b.RecordRequest(ctx, summary)
+	}
+}
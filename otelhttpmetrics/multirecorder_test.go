@@ -0,0 +1,155 @@
+package otelhttpmetrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// blockingRecorder records the requests once released
+type blockingRecorder struct {
+	*otelhttpmetricstest.Recorder
+	release chan struct{}
+}
+
+func (r *blockingRecorder) AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	<-r.release
+	r.Recorder.AddRequests(ctx, quantity, attributes)
+}
+
+func TestMultiRecorderCloseDrainsQueues(t *testing.T) {
+	recorder := &blockingRecorder{Recorder: otelhttpmetricstest.NewRecorder(), release: make(chan struct{})}
+	multi := otelhttpmetrics.MultiRecorder(otelhttpmetrics.Backend(recorder, otelhttpmetrics.WithBackendQueue(4)))
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		multi.AddRequests(ctx, 1, nil)
+	}
+	close(recorder.release)
+	if err := multi.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if n := len(recorder.Filter(otelhttpmetricstest.KindRequests, nil)); n != 4 {
+		t.Errorf("expected the 4 queued requests to be recorded on close, got %d", n)
+	}
+
+	// measurements following Close are recorded synchronously
+	multi.AddRequests(ctx, 1, nil)
+	if n := len(recorder.Filter(otelhttpmetricstest.KindRequests, nil)); n != 5 {
+		t.Errorf("expected the request following close to be recorded, got %d requests", n)
+	}
+	if err := multi.Close(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+}
+
+// panickingRecorder panics on every request
+type panickingRecorder struct {
+	*otelhttpmetricstest.Recorder
+}
+
+func (panickingRecorder) AddRequests(context.Context, int64, []attribute.KeyValue) {
+	panic("recorder failed")
+}
+
+func TestMultiRecorderFanOut(t *testing.T) {
+	reported := captureErrors(t)
+
+	first, second := otelhttpmetricstest.NewRecorder(), otelhttpmetricstest.NewRecorder()
+	multi := otelhttpmetrics.MultiRecorder(
+		first,
+		panickingRecorder{otelhttpmetricstest.NewRecorder()},
+		otelhttpmetrics.Backend(second, otelhttpmetrics.WithBackendAttributeFilter(attribute.NewDenyKeysFilter("secret"))),
+	)
+	multi.AddRequests(context.Background(), 1, []attribute.KeyValue{attribute.String("route", "/"), attribute.String("secret", "token")})
+
+	if n := len(first.Filter(otelhttpmetricstest.KindRequests, nil)); n != 1 {
+		t.Errorf("expected the first recorder to record the request, got %d", n)
+	}
+	requests := second.Filter(otelhttpmetricstest.KindRequests, nil)
+	if len(requests) != 1 {
+		t.Fatalf("expected the recorder following the panicking one to record the request, got %d", len(requests))
+	}
+	if _, ok := requests[0].Attributes.Value("secret"); ok {
+		t.Errorf("expected the filtered attribute to be dropped, got %v", requests[0].Attributes)
+	}
+	if errs := reported(); len(errs) != 1 {
+		t.Errorf("expected the panic to be reported, got %v", errs)
+	}
+}
+
+func TestMultiRecorderQueueFullDrops(t *testing.T) {
+	reported := captureErrors(t)
+
+	recorder := &blockingRecorder{Recorder: otelhttpmetricstest.NewRecorder(), release: make(chan struct{})}
+	multi := otelhttpmetrics.MultiRecorder(otelhttpmetrics.Backend(recorder, otelhttpmetrics.WithBackendQueue(1)))
+	// the recorder being blocked, at most one request is being recorded and one queued
+	for i := 0; i < 3; i++ {
+		multi.AddRequests(context.Background(), 1, nil)
+	}
+	close(recorder.release)
+	if err := multi.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if n := len(recorder.Filter(otelhttpmetricstest.KindRequests, nil)); n == 0 || n > 2 {
+		t.Errorf("expected 1 or 2 requests to be recorded while the queue was full, got %d", n)
+	}
+	if len(reported()) == 0 {
+		t.Error("expected the dropped requests to be reported")
+	}
+}
+
+// plainRecorder implements none of the optional recorder interfaces
+type plainRecorder struct {
+	otelhttpmetrics.Recorder
+}
+
+func TestMultiRecorderOptionalInterfaces(t *testing.T) {
+	plain := plainRecorder{otelhttpmetricstest.NewRecorder()}
+	for _, tt := range []struct {
+		name     string
+		recorder otelhttpmetrics.Recorder
+		// optional is whether one of the recorders wrapped implements the optional interfaces
+		optional bool
+	}{
+		{"multi", otelhttpmetrics.MultiRecorder(plain, otelhttpmetrics.Backend(plain)), false},
+		{"decorator", otelhttpmetrics.AddStaticAttributes(plain, attribute.String("deployment.environment", "test")), false},
+		{"stacked", otelhttpmetrics.MultiRecorder(otelhttpmetrics.FilterKeys(plain, "http.host"), plain), false},
+		{"multi implementing", otelhttpmetrics.MultiRecorder(plain, otelhttpmetricstest.NewRecorder()), true},
+		{"stacked implementing", otelhttpmetrics.MultiRecorder(plain, otelhttpmetrics.FilterKeys(otelhttpmetricstest.NewRecorder(), "http.host")), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			body := io.NopCloser(strings.NewReader("ok"))
+			var traced bool
+			base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				traced = httptrace.ContextClientTrace(r.Context()) != nil
+				return &http.Response{StatusCode: http.StatusOK, Body: body, Request: r}, nil
+			})
+			client := &http.Client{Transport: otelhttpmetrics.NewTransport(base, otelhttpmetrics.WithRecorder(tt.recorder),
+				otelhttpmetrics.WithRecordSizeDisabled(), otelhttpmetrics.WithClientTrace(), otelhttpmetrics.WithConnectionPoolMetrics())}
+
+			response, err := client.Get("http://example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = response.Body.Close()
+
+			// the transport installs the httptrace hooks for the phases and the pool, and wraps the body
+			// for the time to body close and the summaries, only when a recorder records them
+			if traced != tt.optional {
+				t.Errorf("got httptrace hooks %v, want %v", traced, tt.optional)
+			}
+			if wrapped := response.Body != body; wrapped != tt.optional {
+				t.Errorf("got the response body wrapped %v, want %v", wrapped, tt.optional)
+			}
+		})
+	}
+}
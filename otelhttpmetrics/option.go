@@ -2,8 +2,12 @@ package otelhttpmetrics
 
 import (
 	"net/http"
+	"time"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Option applies a configuration to the given config
@@ -18,7 +22,7 @@ func (fn optionFunc) apply(cfg *config) {
 }
 
 // WithAttributes sets a func using which what attributes to be recorded can be specified.
-// By default the DefaultAttributes is used
+// By default the DefaultAttributes is used, or StableAttributes following the SemconvMode
 func WithAttributes(attributes func(*http.Request) []attribute.KeyValue) Option {
 	return optionFunc(func(cfg *config) {
 		cfg.attributes = attributes
@@ -50,6 +54,7 @@ func WithRecordSizeDisabled() Option {
 }
 
 // WithGroupedStatus determines whether to group the response status codes or not. If true 2xx, 3xx will be stored
+// Only the old http.status_code attribute is grouped, the stable http.response.status_code is always the exact code
 // By default the groupedStatus is true
 func WithGroupedStatusDisabled() Option {
 	return optionFunc(func(cfg *config) {
@@ -72,3 +77,146 @@ func WithShouldRecordFunc(shouldRecord func(*http.Request) bool) Option {
 		cfg.shouldRecord = shouldRecord
 	})
 }
+
+// WithServeMux sets the ServeMux used to look up the route of a request served by the handler.
+// By default the handler passed to NewHandler is used when it is a *http.ServeMux
+func WithServeMux(mux *http.ServeMux) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.mux = mux
+	})
+}
+
+// WithSemconvMode sets which HTTP semantic conventions the metric names and attributes follow
+// By default the mode is read from the OTEL_SEMCONV_STABILITY_OPT_IN environment variable
+func WithSemconvMode(mode SemconvMode) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.semconvMode = mode
+	})
+}
+
+// WithDurationBuckets sets the bucket boundaries advised for the duration histogram.
+// LatencySensitiveDurationBuckets and BatchDurationBuckets can be used as presets
+// By default the SDK boundaries are used, or the ones of the stable HTTP semantic conventions
+func WithDurationBuckets(buckets ...time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.durationBuckets = buckets
+	})
+}
+
+// WithSizeBuckets sets the bucket boundaries in bytes advised for the request and response size histograms.
+// LatencySensitiveSizeBuckets and BatchSizeBuckets can be used as presets
+// By default the SDK boundaries are used
+func WithSizeBuckets(buckets ...float64) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.sizeBuckets = buckets
+	})
+}
+
+// WithMeterProvider sets the MeterProvider used to create the open telemetry recorder
+// By default the global MeterProvider is used
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.meterProvider = provider
+	})
+}
+
+// WithClientTrace determines whether to record the phases of outgoing requests made through the transport,
+// using the hooks of httptrace. The DNS, connect, TLS, connection wait and first response byte durations
+// are recorded when the recorder implements ClientTraceRecorder
+// By default the phases are not recorded
+func WithClientTrace() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.clientTrace = true
+	})
+}
+
+// WithConnectionPoolMetrics determines whether to record the connections of the transport per server,
+// using the hooks of httptrace. The active and idle connections are observed along with the number of
+// new, reused and closed by peer connections, when the recorder implements ConnectionPoolRecorder.
+// The transport is expected to live as long as the MeterProvider, which keeps observing its connections
+// By default the connections are not recorded
+func WithConnectionPoolMetrics() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.connectionPool = true
+	})
+}
+
+// WithTracing determines whether the transport starts a client span for each round trip and injects
+// its context in the request headers. The span has the attributes of the metrics, see WithAttributes
+// By default no span is started
+func WithTracing() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.tracing = true
+	})
+}
+
+// WithTracerProvider sets the TracerProvider used to start the client spans when WithTracing is used
+// By default the global TracerProvider is used
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.tracerProvider = provider
+	})
+}
+
+// WithPropagators sets the propagators used to inject the context of the client spans in the request headers
+// By default the global TextMapPropagator is used
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.propagators = propagators
+	})
+}
+
+// WithPeerService sets the peer.service attribute recorded by the transport, naming the service called.
+// ContextWithPeerService changes it for the requests of a context
+// By default no peer.service attribute is recorded
+func WithPeerService(peerService string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.peerService = peerService
+	})
+}
+
+// WithEnvConfig determines whether to read the configuration from the environment variables, see EnvDisabled
+// and the variables following it. The environment variables take precedence over the defaults
+// and the options take precedence over the environment variables
+// By default the environment variables are not read, except OTEL_SEMCONV_STABILITY_OPT_IN
+func WithEnvConfig() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.envConfig = true
+	})
+}
+
+// WithMetricsPrefix sets the prefix of the metric names of the open telemetry recorder, e.g. myapp.http.client.duration
+// By default the metric names are not prefixed
+func WithMetricsPrefix(prefix string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.metricsPrefix = prefix
+	})
+}
+
+// WithExcludedPaths sets the paths of the requests which are not recorded, in addition to WithShouldRecordFunc
+// By default no path is excluded
+func WithExcludedPaths(paths ...string) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.excludedPaths = paths
+	})
+}
+
+// WithRules sets the declarative rules including, excluding or adding attributes to the requests, in addition
+// to WithShouldRecordFunc. The rules match the route set with ContextWithRoute for the transport and the
+// ServeMux pattern for the handler. They are either fixed, see ParseRules, or reloaded from a file, see WatchRules
+// By default no rules are applied
+func WithRules(rules RulesProvider) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.rules = rules
+	})
+}
+
+// WithClock sets the func returning the current time, used to measure the duration of the requests,
+// the phases recorded by WithClientTrace and the time the connections followed by WithConnectionPoolMetrics are idle.
+// It makes the durations deterministic in tests
+// By default time.Now is used
+func WithClock(now func() time.Time) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.now = now
+	})
+}
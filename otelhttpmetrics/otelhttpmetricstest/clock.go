@@ -0,0 +1,37 @@
+package otelhttpmetricstest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a clock advanced manually, safe for concurrent use.
+// Its Now method is passed to the handler or the transport using WithClock, for deterministic durations:
+//
+//	clock := otelhttpmetricstest.NewClock(time.Time{})
+//	handler := otelhttpmetrics.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//		clock.Advance(time.Second)
+//	}), otelhttpmetrics.WithClock(clock.Now))
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock set to the given time
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by the duration
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
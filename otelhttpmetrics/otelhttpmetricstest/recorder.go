@@ -0,0 +1,268 @@
+// Package otelhttpmetricstest provides utilities to test the code recording metrics using otelhttpmetrics:
+// an in-memory Recorder, a manual Clock and a Reader collecting the data points of the open telemetry recorder.
+package otelhttpmetricstest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"go.opentelemetry.io/otel/attribute"
+	semconvstable "go.opentelemetry.io/otel/semconv/v1.21.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// The kinds of the measurements, one per method of the recorders
+const (
+	KindRequests        = "requests"
+	KindDuration        = "duration"
+	KindRequestSize     = "request_size"
+	KindRequestBodySize = "request_body_size"
+	KindResponseSize    = "response_size"
+	KindInflight        = "inflight"
+	KindTimeToFirstByte = "time_to_first_byte"
+	KindTimeToBodyClose = "time_to_body_close"
+	KindClientPhase     = "client_phase"
+	KindConnections     = "connections"
+)
+
+// Measurement is a call made to the Recorder
+type Measurement struct {
+	Kind string
+	// Name is the phase of KindClientPhase and the event of KindConnections, empty for the other kinds
+	Name string
+	// Value is the quantity or the size in bytes, zero for the durations
+	Value int64
+	// Duration is the duration, zero for the other kinds
+	Duration   time.Duration
+	Attributes attribute.Set
+}
+
+// Route returns the http.route attribute of the measurement, or its http.target attribute
+// for the outgoing requests without a route
+func (m Measurement) Route() string {
+	if route, ok := m.Attributes.Value(semconv.HTTPRouteKey); ok {
+		return route.AsString()
+	}
+	target, _ := m.Attributes.Value(semconv.HTTPTargetKey)
+	return target.AsString()
+}
+
+// Status returns the status code attribute of the measurement, in the old or the stable semantic conventions,
+// or 0 when it has none
+func (m Measurement) Status() int {
+	if status, ok := m.Attributes.Value(semconv.HTTPStatusCodeKey); ok {
+		return int(status.AsInt64())
+	}
+	if status, ok := m.Attributes.Value(semconvstable.HTTPResponseStatusCodeKey); ok {
+		return int(status.AsInt64())
+	}
+	return 0
+}
+
+// Recorder is a otelhttpmetrics.Recorder keeping the measurements in memory, safe for concurrent use.
+// It implements the optional recorder interfaces as well, and is passed to the handler or the transport
+// using WithRecorder
+type Recorder struct {
+	mu           sync.Mutex
+	measurements []Measurement
+	summaries    []otelhttpmetrics.RequestSummary
+	pools        []func() []otelhttpmetrics.ConnectionPoolStats
+}
+
+var (
+	_ otelhttpmetrics.Recorder                = (*Recorder)(nil)
+	_ otelhttpmetrics.RequestBodySizeRecorder = (*Recorder)(nil)
+	_ otelhttpmetrics.BodyRecorder            = (*Recorder)(nil)
+	_ otelhttpmetrics.ClientTraceRecorder     = (*Recorder)(nil)
+	_ otelhttpmetrics.ConnectionPoolRecorder  = (*Recorder)(nil)
+	_ otelhttpmetrics.SummaryRecorder         = (*Recorder)(nil)
+)
+
+// NewRecorder returns an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) record(kind, name string, value int64, duration time.Duration, attributes []attribute.KeyValue) {
+	// NewSet sorts the attributes in place, the slice of the caller is left untouched
+	attributes = append([]attribute.KeyValue(nil), attributes...)
+	m := Measurement{
+		Kind:       kind,
+		Name:       name,
+		Value:      value,
+		Duration:   duration,
+		Attributes: attribute.NewSet(attributes...),
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.measurements = append(r.measurements, m)
+}
+
+// AddRequests records a measurement of KindRequests
+func (r *Recorder) AddRequests(_ context.Context, quantity int64, attributes []attribute.KeyValue) {
+	r.record(KindRequests, "", quantity, 0, attributes)
+}
+
+// ObserveHTTPRequestDuration records a measurement of KindDuration
+func (r *Recorder) ObserveHTTPRequestDuration(_ context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	r.record(KindDuration, "", 0, duration, attributes)
+}
+
+// ObserveHTTPRequestSize records a measurement of KindRequestSize
+func (r *Recorder) ObserveHTTPRequestSize(_ context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	r.record(KindRequestSize, "", sizeBytes, 0, attributes)
+}
+
+// ObserveHTTPRequestBodySize records a measurement of KindRequestBodySize
+func (r *Recorder) ObserveHTTPRequestBodySize(_ context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	r.record(KindRequestBodySize, "", sizeBytes, 0, attributes)
+}
+
+// ObserveHTTPResponseSize records a measurement of KindResponseSize
+func (r *Recorder) ObserveHTTPResponseSize(_ context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	r.record(KindResponseSize, "", sizeBytes, 0, attributes)
+}
+
+// AddInflightRequests records a measurement of KindInflight
+func (r *Recorder) AddInflightRequests(_ context.Context, quantity int64, attributes []attribute.KeyValue) {
+	r.record(KindInflight, "", quantity, 0, attributes)
+}
+
+// ObserveHTTPTimeToFirstByte records a measurement of KindTimeToFirstByte
+func (r *Recorder) ObserveHTTPTimeToFirstByte(_ context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	r.record(KindTimeToFirstByte, "", 0, duration, attributes)
+}
+
+// ObserveHTTPTimeToBodyClose records a measurement of KindTimeToBodyClose
+func (r *Recorder) ObserveHTTPTimeToBodyClose(_ context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	r.record(KindTimeToBodyClose, "", 0, duration, attributes)
+}
+
+// ObserveHTTPClientPhase records a measurement of KindClientPhase named after the phase
+func (r *Recorder) ObserveHTTPClientPhase(_ context.Context, phase string, duration time.Duration, attributes []attribute.KeyValue) {
+	r.record(KindClientPhase, phase, 0, duration, attributes)
+}
+
+// AddConnections records a measurement of KindConnections named after the event
+func (r *Recorder) AddConnections(_ context.Context, event string, quantity int64, attributes []attribute.KeyValue) {
+	r.record(KindConnections, event, quantity, 0, attributes)
+}
+
+// ObserveConnectionPool keeps the func reporting the connections, called by ConnectionPool
+func (r *Recorder) ObserveConnectionPool(observe func() []otelhttpmetrics.ConnectionPoolStats) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pools = append(r.pools, observe)
+	return nil
+}
+
+// ConnectionPool returns the active and idle connections reported by the transports using the recorder
+func (r *Recorder) ConnectionPool() []otelhttpmetrics.ConnectionPoolStats {
+	r.mu.Lock()
+	pools := append([]func() []otelhttpmetrics.ConnectionPoolStats(nil), r.pools...)
+	r.mu.Unlock()
+	var stats []otelhttpmetrics.ConnectionPoolStats
+	for _, observe := range pools {
+		stats = append(stats, observe()...)
+	}
+	return stats
+}
+
+// RecordRequest keeps the summary of the request, returned by Summaries
+func (r *Recorder) RecordRequest(_ context.Context, summary otelhttpmetrics.RequestSummary) {
+	summary.Attributes = append([]attribute.KeyValue(nil), summary.Attributes...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summaries = append(r.summaries, summary)
+}
+
+// Summaries returns a copy of the summaries of the requests recorded so far, in order
+func (r *Recorder) Summaries() []otelhttpmetrics.RequestSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]otelhttpmetrics.RequestSummary(nil), r.summaries...)
+}
+
+// Measurements returns a copy of the measurements recorded so far, in order
+func (r *Recorder) Measurements() []Measurement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Measurement(nil), r.measurements...)
+}
+
+// Filter returns the measurements of the kind for which match returns true, match may be nil
+func (r *Recorder) Filter(kind string, match func(Measurement) bool) []Measurement {
+	var measurements []Measurement
+	for _, m := range r.Measurements() {
+		if m.Kind == kind && (match == nil || match(m)) {
+			measurements = append(measurements, m)
+		}
+	}
+	return measurements
+}
+
+// Reset removes the measurements and the summaries recorded so far
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.measurements = nil
+	r.summaries = nil
+}
+
+// RequestCount returns the number of requests recorded for the route and the status code.
+// The status code is the one recorded, e.g. 200 for all the 2xx responses when the status codes are grouped
+func (r *Recorder) RequestCount(route string, status int) int64 {
+	var count int64
+	for _, m := range r.Filter(KindRequests, matchRequest(route, status)) {
+		count += m.Value
+	}
+	return count
+}
+
+// Inflight returns the number of requests in flight, the sum of the KindInflight measurements
+func (r *Recorder) Inflight() int64 {
+	var count int64
+	for _, m := range r.Filter(KindInflight, nil) {
+		count += m.Value
+	}
+	return count
+}
+
+// Durations returns the durations recorded for the route
+func (r *Recorder) Durations(route string) []time.Duration {
+	var durations []time.Duration
+	for _, m := range r.Filter(KindDuration, matchRequest(route, 0)) {
+		durations = append(durations, m.Duration)
+	}
+	return durations
+}
+
+// AssertRequestCount fails the test when the number of requests recorded for the route and the status code is not n
+func (r *Recorder) AssertRequestCount(t testing.TB, route string, status int, n int64) {
+	t.Helper()
+	if count := r.RequestCount(route, status); count != n {
+		t.Errorf("request count of %s with status %d: got %d, want %d", route, status, count, n)
+	}
+}
+
+// AssertDurationRecorded fails the test when the duration was not recorded for the route
+func (r *Recorder) AssertDurationRecorded(t testing.TB, route string, duration time.Duration) {
+	t.Helper()
+	durations := r.Durations(route)
+	for _, d := range durations {
+		if d == duration {
+			return
+		}
+	}
+	t.Errorf("duration of %s: %v not recorded, got %v", route, duration, durations)
+}
+
+// matchRequest matches the measurements of the route and the status code, any status code when status is 0
+func matchRequest(route string, status int) func(Measurement) bool {
+	return func(m Measurement) bool {
+		return m.Route() == route && (status == 0 || m.Status() == status)
+	}
+}
@@ -15,56 +15,242 @@ const instrumentationName = "github.com/technologize/otel-go-contrib/otelhttpmet
 // has the required methods to be used with the HTTP
 // middlewares.
 type otelRecorder struct {
+	semconvMode           SemconvMode
 	attemptsCounter       metric.Int64UpDownCounter
 	totalDuration         metric.Int64Histogram
 	activeRequestsCounter metric.Int64UpDownCounter
 	requestSize           metric.Int64Histogram
 	responseSize          metric.Int64Histogram
+	timeToFirstByte       metric.Int64Histogram
+	timeToBodyClose       metric.Int64Histogram
+
+	// instruments following the stable HTTP semantic conventions
+	requestDuration         metric.Float64Histogram
+	requestBodySize         metric.Int64Histogram
+	responseBodySize        metric.Int64Histogram
+	responseTimeToFirstByte metric.Float64Histogram
+	responseTimeToBodyClose metric.Float64Histogram
+
+	// histograms of the phases of outgoing requests, by phase
+	phaseDurations       map[string]metric.Int64Histogram
+	stablePhaseDurations map[string]metric.Float64Histogram
+
+	// instruments of the connection pool of the transport
+	meter            metric.Meter
+	openConnections  metric.Int64ObservableUpDownCounter
+	connectionEvents map[string]metric.Int64Counter
 }
 
+// clientPhases are the phases of outgoing requests a histogram is created for
+var clientPhases = []string{PhaseDNS, PhaseConnect, PhaseTLS, PhaseGetConn, PhaseFirstByte}
+
+// connectionEvents are the connection events a counter is created for
+var connectionEvents = []string{ConnectionEventNew, ConnectionEventReused, ConnectionEventClosedByPeer}
+
+// GetRecorder returns the open telemetry recorder for outgoing requests
+// made through the transport. The metric names are prefixed with http.client
+// The global MeterProvider is used, errors creating the instruments are reported using otel.Handle
 func GetRecorder(metricsPrefix string) Recorder {
+	recorder, err := NewRecorder(otel.GetMeterProvider(), metricsPrefix)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return recorder
+}
+
+// GetServerRecorder returns the open telemetry recorder for incoming requests
+// served through the handler. The metric names are prefixed with http.server
+// The global MeterProvider is used, errors creating the instruments are reported using otel.Handle
+func GetServerRecorder(metricsPrefix string) Recorder {
+	recorder, err := NewServerRecorder(otel.GetMeterProvider(), metricsPrefix)
+	if err != nil {
+		otel.Handle(err)
+	}
+	return recorder
+}
+
+// NewRecorder returns the open telemetry recorder for outgoing requests using the given MeterProvider.
+// The semantic conventions followed are read from the OTEL_SEMCONV_STABILITY_OPT_IN environment variable
+// When an instrument cannot be created the error is returned along with a recorder not recording on it
+func NewRecorder(provider metric.MeterProvider, metricsPrefix string) (Recorder, error) {
+	return newOtelRecorder(provider, metricsPrefix, "http.client", defaultConfig())
+}
+
+// NewServerRecorder returns the open telemetry recorder for incoming requests using the given MeterProvider.
+// The semantic conventions followed are read from the OTEL_SEMCONV_STABILITY_OPT_IN environment variable
+// When an instrument cannot be created the error is returned along with a recorder not recording on it
+func NewServerRecorder(provider metric.MeterProvider, metricsPrefix string) (Recorder, error) {
+	return newOtelRecorder(provider, metricsPrefix, "http.server", defaultConfig())
+}
+
+func newOtelRecorder(provider metric.MeterProvider, metricsPrefix, side string, cfg *config) (Recorder, error) {
 	metricName := func(metricName string) string {
 		if len(metricsPrefix) > 0 {
-			return metricsPrefix + "." + metricName
+			return metricsPrefix + "." + side + "." + metricName
+		}
+		return side + "." + metricName
+	}
+	semconvMode := cfg.semconvMode
+	i := &instruments{meter: provider.Meter(instrumentationName, metric.WithInstrumentationVersion(SemVersion()))}
+	r := &otelRecorder{semconvMode: semconvMode, meter: i.meter}
+	if semconvMode.emitOld() {
+		r.attemptsCounter = i.int64UpDownCounter(metricName("request_count"), metric.WithDescription("Number of Requests"), metric.WithUnit("Count"))
+		r.totalDuration = i.int64Histogram(metricName("duration"), int64HistogramOptions(durationBoundaries(cfg.durationBuckets, time.Millisecond), metric.WithDescription("Time Taken by request"), metric.WithUnit("Milliseconds"))...)
+		r.activeRequestsCounter = i.int64UpDownCounter(metricName("active_requests"), metric.WithDescription("Number of requests inflight"), metric.WithUnit("Count"))
+		r.requestSize = i.int64Histogram(metricName("request_content_length"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Request Size"), metric.WithUnit("Bytes"))...)
+		r.responseSize = i.int64Histogram(metricName("response_content_length"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Response Size"), metric.WithUnit("Bytes"))...)
+		if side == "http.client" {
+			r.timeToFirstByte = i.int64Histogram(metricName("time_to_first_byte"), int64HistogramOptions(durationBoundaries(cfg.durationBuckets, time.Millisecond), metric.WithDescription("Time Taken until the response headers were received"), metric.WithUnit("Milliseconds"))...)
+			r.timeToBodyClose = i.int64Histogram(metricName("time_to_body_close"), int64HistogramOptions(durationBoundaries(cfg.durationBuckets, time.Millisecond), metric.WithDescription("Time Taken until the response body was closed"), metric.WithUnit("Milliseconds"))...)
+			r.phaseDurations = make(map[string]metric.Int64Histogram, len(clientPhases))
+			for _, phase := range clientPhases {
+				r.phaseDurations[phase] = i.int64Histogram(metricName(phase+"_duration"), int64HistogramOptions(durationBoundaries(cfg.durationBuckets, time.Millisecond), metric.WithDescription("Time Taken by the "+phase+" phase of the request"), metric.WithUnit("Milliseconds"))...)
+			}
 		}
-		return metricName
 	}
-	meter := otel.Meter(instrumentationName, metric.WithInstrumentationVersion(SemVersion()))
-	attemptsCounter, _ := meter.Int64UpDownCounter(metricName("http.client.request_count"), metric.WithDescription("Number of Requests"), metric.WithUnit("Count"))
-	totalDuration, _ := meter.Int64Histogram(metricName("http.client.duration"), metric.WithDescription("Time Taken by request"), metric.WithUnit("Milliseconds"))
-	activeRequestsCounter, _ := meter.Int64UpDownCounter(metricName("http.client.active_requests"), metric.WithDescription("Number of requests inflight"), metric.WithUnit("Count"))
-	requestSize, _ := meter.Int64Histogram(metricName("http.client.request_content_length"), metric.WithDescription("Request Size"), metric.WithUnit("Bytes"))
-	responseSize, _ := meter.Int64Histogram(metricName("http.client.response_content_length"), metric.WithDescription("Response Size"), metric.WithUnit("Bytes"))
-	return &otelRecorder{
-		attemptsCounter:       attemptsCounter,
-		totalDuration:         totalDuration,
-		activeRequestsCounter: activeRequestsCounter,
-		requestSize:           requestSize,
-		responseSize:          responseSize,
+	if semconvMode == SemconvStable {
+		r.activeRequestsCounter = i.int64UpDownCounter(metricName("active_requests"), metric.WithDescription("Number of active HTTP requests"), metric.WithUnit("{request}"))
 	}
+	if semconvMode.emitStable() {
+		durationBuckets := cfg.durationBuckets
+		if len(durationBuckets) == 0 {
+			durationBuckets = stableDurationBuckets
+		}
+		r.requestDuration = i.float64Histogram(metricName("request.duration"), float64HistogramOptions(durationBoundaries(durationBuckets, time.Second), metric.WithDescription("Duration of HTTP requests"), metric.WithUnit("s"))...)
+		r.requestBodySize = i.int64Histogram(metricName("request.body.size"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Size of HTTP request bodies"), metric.WithUnit("By"))...)
+		r.responseBodySize = i.int64Histogram(metricName("response.body.size"), int64HistogramOptions(cfg.sizeBuckets, metric.WithDescription("Size of HTTP response bodies"), metric.WithUnit("By"))...)
+		if side == "http.client" {
+			r.responseTimeToFirstByte = i.float64Histogram(metricName("response.time_to_first_byte"), float64HistogramOptions(durationBoundaries(durationBuckets, time.Second), metric.WithDescription("Duration until the HTTP response headers were received"), metric.WithUnit("s"))...)
+			r.responseTimeToBodyClose = i.float64Histogram(metricName("response.time_to_body_close"), float64HistogramOptions(durationBoundaries(durationBuckets, time.Second), metric.WithDescription("Duration until the HTTP response body was closed"), metric.WithUnit("s"))...)
+			r.stablePhaseDurations = make(map[string]metric.Float64Histogram, len(clientPhases))
+			for _, phase := range clientPhases {
+				r.stablePhaseDurations[phase] = i.float64Histogram(metricName(phase+".duration"), float64HistogramOptions(durationBoundaries(durationBuckets, time.Second), metric.WithDescription("Duration of the "+phase+" phase of HTTP requests"), metric.WithUnit("s"))...)
+			}
+		}
+	}
+	if side == "http.client" {
+		// the connection pool metrics are the same in both conventions
+		r.openConnections = i.int64ObservableUpDownCounter(metricName("open_connections"), metric.WithDescription("Number of connections of the transport, by state"), metric.WithUnit("{connection}"))
+		r.connectionEvents = make(map[string]metric.Int64Counter, len(connectionEvents))
+		for _, event := range connectionEvents {
+			r.connectionEvents[event] = i.int64Counter(metricName("connections."+event), metric.WithDescription("Number of "+event+" connections"), metric.WithUnit("{connection}"))
+		}
+	}
+	return r, i.err
+}
+
+// oldAttributes returns the attributes of the old metrics, the stable ones are left out when both are emitted.
+func (r *otelRecorder) oldAttributes(attributes []attribute.KeyValue) metric.MeasurementOption {
+	if r.semconvMode == SemconvDuplicate {
+		attributes = withoutKeys(attributes, stableAttributeKeys)
+	}
+	return metric.WithAttributes(attributes...)
+}
+
+// stableAttributes returns the attributes of the stable metrics, the old ones are left out when both are emitted.
+func (r *otelRecorder) stableAttributes(attributes []attribute.KeyValue) metric.MeasurementOption {
+	if r.semconvMode == SemconvDuplicate {
+		attributes = withoutKeys(attributes, oldAttributeKeys)
+	}
+	return metric.WithAttributes(attributes...)
 }
 
 // AddRequests increments the number of requests being processed.
+// The stable conventions have no request counter, the count of the duration histogram is used instead.
 func (r *otelRecorder) AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
-	r.attemptsCounter.Add(ctx, quantity, metric.WithAttributes(attributes...))
+	if r.semconvMode.emitOld() {
+		r.attemptsCounter.Add(ctx, quantity, r.oldAttributes(attributes))
+	}
+}
+
+// recordDuration records the duration in milliseconds on the old histogram and in seconds on the stable one.
+// Histograms not created for the side of the recorder are nil and not recorded on.
+func (r *otelRecorder) recordDuration(ctx context.Context, old metric.Int64Histogram, stable metric.Float64Histogram, duration time.Duration, attributes []attribute.KeyValue) {
+	if r.semconvMode.emitOld() && old != nil {
+		old.Record(ctx, int64(duration/time.Millisecond), r.oldAttributes(attributes))
+	}
+	if r.semconvMode.emitStable() && stable != nil {
+		stable.Record(ctx, duration.Seconds(), r.stableAttributes(attributes))
+	}
 }
 
 // ObserveHTTPRequestDuration measures the duration of an HTTP request.
 func (r *otelRecorder) ObserveHTTPRequestDuration(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
-	r.totalDuration.Record(ctx, int64(duration/time.Millisecond), metric.WithAttributes(attributes...))
+	r.recordDuration(ctx, r.totalDuration, r.requestDuration, duration, attributes)
+}
+
+// ObserveHTTPTimeToFirstByte measures the time until the first byte of the response of an HTTP request was received.
+func (r *otelRecorder) ObserveHTTPTimeToFirstByte(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	r.recordDuration(ctx, r.timeToFirstByte, r.responseTimeToFirstByte, duration, attributes)
+}
+
+// ObserveHTTPTimeToBodyClose measures the time until the response body of an HTTP request was closed.
+func (r *otelRecorder) ObserveHTTPTimeToBodyClose(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	r.recordDuration(ctx, r.timeToBodyClose, r.responseTimeToBodyClose, duration, attributes)
+}
+
+// ObserveHTTPClientPhase measures the duration of a phase of an outgoing HTTP request.
+// Phases unknown to the recorder are not recorded.
+func (r *otelRecorder) ObserveHTTPClientPhase(ctx context.Context, phase string, duration time.Duration, attributes []attribute.KeyValue) {
+	old, stable := r.phaseDurations[phase], r.stablePhaseDurations[phase]
+	if old == nil && stable == nil {
+		return
+	}
+	r.recordDuration(ctx, old, stable, duration, attributes)
 }
 
-// ObserveHTTPRequestSize measures the size of an HTTP request in bytes.
+// ObserveHTTPRequestSize measures the approximate size of an HTTP request in bytes.
+// The stable conventions measure the size of the body only, recorded by ObserveHTTPRequestBodySize.
 func (r *otelRecorder) ObserveHTTPRequestSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
-	r.requestSize.Record(ctx, sizeBytes, metric.WithAttributes(attributes...))
+	if r.semconvMode.emitOld() {
+		r.requestSize.Record(ctx, sizeBytes, r.oldAttributes(attributes))
+	}
+}
+
+// ObserveHTTPRequestBodySize measures the size of the body of an HTTP request in bytes.
+func (r *otelRecorder) ObserveHTTPRequestBodySize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	if r.semconvMode.emitStable() {
+		r.requestBodySize.Record(ctx, sizeBytes, r.stableAttributes(attributes))
+	}
 }
 
 // ObserveHTTPResponseSize measures the size of an HTTP response in bytes.
 func (r *otelRecorder) ObserveHTTPResponseSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
-	r.responseSize.Record(ctx, sizeBytes, metric.WithAttributes(attributes...))
+	if r.semconvMode.emitOld() {
+		r.responseSize.Record(ctx, sizeBytes, r.oldAttributes(attributes))
+	}
+	if r.semconvMode.emitStable() {
+		r.responseBodySize.Record(ctx, sizeBytes, r.stableAttributes(attributes))
+	}
 }
 
 // AddInflightRequests increments and decrements the number of inflight request being processed.
+// The metric has the same name in both conventions, so it carries both attribute sets when both are emitted.
 func (r *otelRecorder) AddInflightRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
 	r.activeRequestsCounter.Add(ctx, quantity, metric.WithAttributes(attributes...))
 }
+
+// AddConnections increments the number of connections which went through an event.
+// Events unknown to the recorder are not recorded.
+func (r *otelRecorder) AddConnections(ctx context.Context, event string, quantity int64, attributes []attribute.KeyValue) {
+	if counter, ok := r.connectionEvents[event]; ok {
+		counter.Add(ctx, quantity, metric.WithAttributes(attributes...))
+	}
+}
+
+// ObserveConnectionPool registers the func reporting the active and idle connections of each server.
+// Each call registers a callback, the connections observed for the same server are summed.
+func (r *otelRecorder) ObserveConnectionPool(observe func() []ConnectionPoolStats) error {
+	if r.openConnections == nil {
+		return nil
+	}
+	_, err := r.meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, stats := range observe() {
+			attrs := stats.Attributes[:len(stats.Attributes):len(stats.Attributes)]
+			o.ObserveInt64(r.openConnections, stats.Active, metric.WithAttributes(append(attrs, ConnectionStateKey.String(ConnectionStateActive))...))
+			o.ObserveInt64(r.openConnections, stats.Idle, metric.WithAttributes(append(attrs, ConnectionStateKey.String(ConnectionStateIdle))...))
+		}
+		return nil
+	}, r.openConnections)
+	return err
+}
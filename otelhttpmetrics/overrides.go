@@ -0,0 +1,73 @@
+package otelhttpmetrics
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// overrides change the behavior of the transport for the requests of a context
+type overrides struct {
+	skip        bool
+	attributes  []attribute.KeyValue
+	peerService string
+}
+
+type overridesKey struct{}
+
+func overridesFromContext(ctx context.Context) overrides {
+	o, _ := ctx.Value(overridesKey{}).(overrides)
+	return o
+}
+
+func contextWithOverrides(ctx context.Context, update func(o *overrides)) context.Context {
+	o := overridesFromContext(ctx)
+	update(&o)
+	return context.WithValue(ctx, overridesKey{}, o)
+}
+
+// ContextWithoutRecording returns a copy of ctx with which the transport neither records metrics
+// nor starts a span for the requests, e.g. for internal polling
+func ContextWithoutRecording(ctx context.Context) context.Context {
+	return contextWithOverrides(ctx, func(o *overrides) {
+		o.skip = true
+	})
+}
+
+// ContextWithAttributes returns a copy of ctx with which the transport adds the given attributes to
+// those of the requests, such as the name of a logical operation. The attributes are added to the
+// ones already set on ctx, replacing those with the same key
+func ContextWithAttributes(ctx context.Context, attributes ...attribute.KeyValue) context.Context {
+	return contextWithOverrides(ctx, func(o *overrides) {
+		attrs := make([]attribute.KeyValue, 0, len(o.attributes)+len(attributes))
+		attrs = append(attrs, o.attributes...)
+		o.attributes = append(attrs, attributes...)
+	})
+}
+
+// ContextWithPeerService returns a copy of ctx with which the transport records the requests with
+// the given peer.service attribute, in place of the one set with WithPeerService
+func ContextWithPeerService(ctx context.Context, peerService string) context.Context {
+	return contextWithOverrides(ctx, func(o *overrides) {
+		o.peerService = peerService
+	})
+}
+
+// requestAttributes returns the attributes of the request along with the peer.service
+// attribute, the attributes added by the rules and those added by the overrides of its context
+func (cfg *config) requestAttributes(request *http.Request, o overrides, ruleAttributes []attribute.KeyValue) []attribute.KeyValue {
+	// the slice returned by the attributes func is clipped, so that appending leaves its backing array untouched
+	attrs := cfg.attributes(request)
+	attrs = attrs[:len(attrs):len(attrs)]
+	peerService := cfg.peerService
+	if o.peerService != "" {
+		peerService = o.peerService
+	}
+	if peerService != "" {
+		attrs = append(attrs, semconv.PeerServiceKey.String(peerService))
+	}
+	attrs = append(attrs, ruleAttributes...)
+	return append(attrs, o.attributes...)
+}
@@ -0,0 +1,32 @@
+package otelhttpmetrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestTransportLeavesAttributesUntouched(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	// the attributes func returns a slice with spare capacity, whose backing array is shared
+	backing := []attribute.KeyValue{attribute.String("service", "test"), attribute.String("spare", "untouched")}
+	attributes := func(*http.Request) []attribute.KeyValue {
+		return backing[:1]
+	}
+	recorder := otelhttpmetricstest.NewRecorder()
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(server.Client().Transport,
+		otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithAttributes(attributes), otelhttpmetrics.WithPeerService("peer"))}
+	handler := otelhttpmetrics.NewHandler(http.NotFoundHandler(), otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithAttributes(attributes))
+
+	get(t, client, server.URL)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if backing[1] != attribute.String("spare", "untouched") {
+		t.Errorf("the backing array of the attributes was overwritten with %v", backing[1])
+	}
+}
@@ -0,0 +1,114 @@
+package otelhttpmetrics
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// The placeholders replacing the segments of the paths by NormalizePath
+const (
+	PathPlaceholderID    = "{id}"
+	PathPlaceholderUUID  = "{uuid}"
+	PathPlaceholderHash  = "{hash}"
+	PathPlaceholderToken = "{token}"
+)
+
+var (
+	numericSegment = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hashSegment    = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	tokenSegment   = regexp.MustCompile(`^[0-9A-Za-z_\-.~+=]{24,}$`)
+)
+
+// PathRule replaces the parts of the paths matching Pattern with Replacement, as regexp.ReplaceAllString does,
+// for the requests sent to Host. Host is matched with the host of the URL with or without the port,
+// an empty Host applies the rule to all hosts
+type PathRule struct {
+	Host        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+func (rule PathRule) matches(url string, hostname string) bool {
+	return rule.Host == "" || rule.Host == url || rule.Host == hostname
+}
+
+// NormalizePath returns the path with the segments looking like identifiers replaced by placeholders,
+// so that the paths of the requests to the same endpoint make a single time series.
+// Numeric IDs are replaced by {id}, UUIDs by {uuid}, hex hashes of 16 characters or more by {hash}
+// and opaque tokens of 24 characters or more mixing letters and digits by {token}
+func NormalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = normalizeSegment(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func normalizeSegment(segment string) string {
+	switch {
+	case segment == "":
+		return segment
+	case numericSegment.MatchString(segment):
+		return PathPlaceholderID
+	case uuidSegment.MatchString(segment):
+		return PathPlaceholderUUID
+	case hashSegment.MatchString(segment):
+		return PathPlaceholderHash
+	case tokenSegment.MatchString(segment) && strings.ContainsAny(segment, "0123456789") &&
+		strings.IndexFunc(segment, isLetter) >= 0:
+		return PathPlaceholderToken
+	}
+	return segment
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// NewPathNormalizer returns a func returning the normalized path of a request.
+// The rules of the host of the request are applied in order first, then the path is normalized by NormalizePath
+func NewPathNormalizer(rules ...PathRule) func(*http.Request) string {
+	return func(request *http.Request) string {
+		if request.URL == nil {
+			return ""
+		}
+		path := request.URL.Path
+		for _, rule := range rules {
+			if rule.matches(request.URL.Host, request.URL.Hostname()) {
+				path = rule.Pattern.ReplaceAllString(path, rule.Replacement)
+			}
+		}
+		return NormalizePath(path)
+	}
+}
+
+// NewSafeAttributes returns attributes like DefaultAttributes with a bounded cardinality:
+// the path of http.target is normalized using the given rules and NormalizePath,
+// and methods not known to the HTTP semantic conventions are recorded as _OTHER.
+// The route set with ContextWithRoute is recorded as http.route in place of the normalized path
+func NewSafeAttributes(rules ...PathRule) func(*http.Request) []attribute.KeyValue {
+	normalizePath := NewPathNormalizer(rules...)
+	return func(request *http.Request) []attribute.KeyValue {
+		attrs := []attribute.KeyValue{
+			semconv.HTTPMethodKey.String(stableMethod(request.Method)),
+		}
+		if request.Host != "" {
+			attrs = append(attrs, semconv.HTTPHostKey.String(request.Host))
+		}
+		if route := RouteFromContext(request.Context()); route != "" {
+			attrs = append(attrs, semconv.HTTPRouteKey.String(route))
+		} else if target := normalizePath(request); target != "" {
+			attrs = append(attrs, semconv.HTTPTargetKey.String(target))
+		}
+		return attrs
+	}
+}
+
+// SafeAttributes are the attributes returned by NewSafeAttributes without rules,
+// to be used in place of DefaultAttributes when the paths contain identifiers
+var SafeAttributes = NewSafeAttributes()
@@ -0,0 +1,85 @@
+package otelhttpmetrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+func TestNormalizePath(t *testing.T) {
+	for path, want := range map[string]string{
+		"":                    "",
+		"/":                   "/",
+		"/users/42":           "/users/{id}",
+		"/users/42/orders/7/": "/users/{id}/orders/{id}/",
+		"/files/3f2504e0-4f89-11d3-9a0c-0305e82c3301":       "/files/{uuid}",
+		"/blobs/deadbeefdeadbeef":                           "/blobs/{hash}",
+		"/commits/9FCEB02A3B1C4E5F6A7B8C9D0E1F2A3B4C5D6E7F": "/commits/{hash}",
+		"/reset/eyJhbGciOiJIUzI1NiJ9abc123xyz":              "/reset/{token}",
+		// ordinary words are kept, even when they only use hex letters or are long
+		"/users/me/settings":                "/users/me/settings",
+		"/cafe":                             "/cafe",
+		"/added/decade/facade":              "/added/decade/facade",
+		"/deadbeef":                         "/deadbeef",
+		"/internationalization-preferences": "/internationalization-preferences",
+		// version segments mix letters and digits but are too short to be tokens
+		"/v2/users":             "/v2/users",
+		"/api/v10/items/x86_64": "/api/v10/items/x86_64",
+	} {
+		if got := otelhttpmetrics.NormalizePath(path); got != want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestPathNormalizerRules(t *testing.T) {
+	normalize := otelhttpmetrics.NewPathNormalizer(
+		otelhttpmetrics.PathRule{Host: "api.example.com", Pattern: regexp.MustCompile(`^/users/[0-9]+`), Replacement: "/users/{user}"},
+		otelhttpmetrics.PathRule{Host: "cdn.example.com:8443", Pattern: regexp.MustCompile(`^/assets/.*`), Replacement: "/assets/{asset}"},
+		otelhttpmetrics.PathRule{Pattern: regexp.MustCompile(`/sku-[A-Z0-9]+`), Replacement: "/{sku}"},
+	)
+	for url, want := range map[string]string{
+		// the rules of the host take precedence over the defaults, with or without the port
+		"http://api.example.com/users/42/orders/7":     "/users/{user}/orders/{id}",
+		"http://api.example.com:8080/users/42":         "/users/{user}",
+		"https://cdn.example.com:8443/assets/logo.png": "/assets/{asset}",
+		// the rules of other hosts are not applied, the defaults are
+		"http://other.example.com/users/42":       "/users/{id}",
+		"https://cdn.example.com/assets/logo.png": "/assets/logo.png",
+		// a rule without host applies to all of them
+		"http://other.example.com/items/sku-AB12": "/items/{sku}",
+	} {
+		if got := normalize(httptest.NewRequest(http.MethodGet, url, nil)); got != want {
+			t.Errorf("normalized path of %s = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestSafeAttributes(t *testing.T) {
+	request := httptest.NewRequest("PURGE", "http://example.com/users/42", nil)
+	got := attribute.NewSet(otelhttpmetrics.SafeAttributes(request)...)
+	want := attribute.NewSet(
+		semconv.HTTPMethodKey.String("_OTHER"),
+		semconv.HTTPHostKey.String("example.com"),
+		semconv.HTTPTargetKey.String("/users/{id}"),
+	)
+	if !got.Equals(&want) {
+		t.Errorf("got attributes %v, want %v", got.ToSlice(), want.ToSlice())
+	}
+
+	// the route set on the context replaces the normalized path
+	request = request.WithContext(otelhttpmetrics.ContextWithRoute(context.Background(), "/users/:id"))
+	got = attribute.NewSet(otelhttpmetrics.SafeAttributes(request)...)
+	if route, _ := got.Value(semconv.HTTPRouteKey); route.AsString() != "/users/:id" {
+		t.Errorf("got route %q, want /users/:id", route.AsString())
+	}
+	if _, ok := got.Value(semconv.HTTPTargetKey); ok {
+		t.Errorf("expected no http.target along with the route, got %v", got.ToSlice())
+	}
+}
@@ -0,0 +1,321 @@
+package otelhttpmetrics
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconvstable "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// ConnectionStateKey is the attribute telling whether the connections of the pool are in use or idle
+const ConnectionStateKey = attribute.Key("http.connection.state")
+
+// The states of the connections reported by the pool metrics
+const (
+	ConnectionStateActive = "active"
+	ConnectionStateIdle   = "idle"
+)
+
+// The connection events counted when WithConnectionPoolMetrics is used
+const (
+	// ConnectionEventNew is a connection dialed for a request
+	ConnectionEventNew = "new"
+	// ConnectionEventReused is a connection taken from the pool for a request
+	ConnectionEventReused = "reused"
+	// ConnectionEventClosedByPeer is a connection the server closed, either by asking to or by failing a request on it
+	ConnectionEventClosedByPeer = "closed_by_peer"
+)
+
+// ConnectionPoolStats are the number of active and idle connections to a server
+type ConnectionPoolStats struct {
+	Attributes []attribute.KeyValue
+	Active     int64
+	Idle       int64
+}
+
+// defaultIdleConnTimeout is the IdleConnTimeout of http.DefaultTransport, assumed when the transport
+// keeps idle connections without a timeout or is not a *http.Transport, as servers close them eventually
+const defaultIdleConnTimeout = 90 * time.Second
+
+// connPool follows the connections of a transport using the hooks of httptrace.
+// The transport does not tell when an idle connection is closed, so idle connections are forgotten
+// once the IdleConnTimeout of the transport passed, when they fail to be reused, when a new connection
+// to their server is dialed, as the transport reuses idle connections first, or beyond the number
+// of idle connections the transport keeps per server.
+type connPool struct {
+	mu          sync.Mutex
+	conns       map[net.Conn]*pooledConn
+	idleTimeout time.Duration
+	// maxIdlePerServer is the MaxIdleConnsPerHost of the transport, 0 when it is not known
+	maxIdlePerServer int
+	// now returns the current time, see WithClock
+	now func() time.Time
+}
+
+type pooledConn struct {
+	attributes attribute.Set
+	// streams is the number of requests using the connection, more than one with HTTP/2
+	streams   int
+	idleSince time.Time
+}
+
+func newConnPool(base http.RoundTripper, now func() time.Time) *connPool {
+	p := &connPool{
+		conns:       make(map[net.Conn]*pooledConn),
+		idleTimeout: defaultIdleConnTimeout,
+		now:         now,
+	}
+	if t, ok := base.(*http.Transport); ok {
+		if t.IdleConnTimeout > 0 {
+			p.idleTimeout = t.IdleConnTimeout
+		}
+		p.maxIdlePerServer = t.MaxIdleConnsPerHost
+		if p.maxIdlePerServer <= 0 {
+			p.maxIdlePerServer = http.DefaultMaxIdleConnsPerHost
+		}
+	}
+	return p
+}
+
+// acquire marks the connection as used by a request started at start. A new connection means
+// that no idle connection to the server could be reused, those idle since before are closed.
+func (p *connPool) acquire(conn net.Conn, attributes attribute.Set, reused bool, start time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !reused {
+		for other, c := range p.conns {
+			if c.streams == 0 && c.idleSince.Before(start) && c.attributes.Equals(&attributes) {
+				delete(p.conns, other)
+			}
+		}
+	}
+	c, ok := p.conns[conn]
+	if !ok {
+		c = &pooledConn{}
+		p.conns[conn] = c
+	}
+	// connections to a proxy are shared by the servers requested through it
+	c.attributes = attributes
+	c.streams++
+}
+
+func (p *connPool) release(conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c, ok := p.conns[conn]
+	if !ok {
+		return
+	}
+	if c.streams > 0 {
+		c.streams--
+	}
+	if c.streams == 0 {
+		c.idleSince = p.now()
+	}
+}
+
+func (p *connPool) remove(conn net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.conns, conn)
+}
+
+// stats returns the number of active and idle connections of each server having connections
+func (p *connPool) stats() []ConnectionPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	idle := make(map[attribute.Distinct][]net.Conn)
+	for conn, c := range p.conns {
+		if c.streams > 0 {
+			continue
+		}
+		if now.Sub(c.idleSince) > p.idleTimeout {
+			delete(p.conns, conn)
+			continue
+		}
+		idle[c.attributes.Equivalent()] = append(idle[c.attributes.Equivalent()], conn)
+	}
+	if p.maxIdlePerServer > 0 {
+		for _, conns := range idle {
+			if len(conns) <= p.maxIdlePerServer {
+				continue
+			}
+			// the transport keeps the most recently used connections
+			sort.Slice(conns, func(i, j int) bool {
+				return p.conns[conns[i]].idleSince.After(p.conns[conns[j]].idleSince)
+			})
+			for _, conn := range conns[p.maxIdlePerServer:] {
+				delete(p.conns, conn)
+			}
+		}
+	}
+
+	index := make(map[attribute.Distinct]int)
+	var stats []ConnectionPoolStats
+	for _, c := range p.conns {
+		i, ok := index[c.attributes.Equivalent()]
+		if !ok {
+			i = len(stats)
+			index[c.attributes.Equivalent()] = i
+			stats = append(stats, ConnectionPoolStats{Attributes: c.attributes.ToSlice()})
+		}
+		if c.streams > 0 {
+			stats[i].Active++
+		} else {
+			stats[i].Idle++
+		}
+	}
+	return stats
+}
+
+// connTrace follows the connection used by a request
+type connTrace struct {
+	mu         sync.Mutex
+	once       sync.Once
+	ctx        context.Context
+	pool       *connPool
+	recorder   ConnectionPoolRecorder
+	request    *http.Request
+	attributes []attribute.KeyValue
+	set        attribute.Set
+	conn       net.Conn
+	reused     bool
+	start      time.Time
+	http2      bool
+	// putIdle is set once the transport returned the connection to the pool or refused to
+	putIdle bool
+}
+
+func (p *connPool) trace(ctx context.Context, recorder ConnectionPoolRecorder, request *http.Request) *connTrace {
+	attributes := serverAttributes(request)
+	return &connTrace{
+		ctx:        ctx,
+		pool:       p,
+		recorder:   recorder,
+		request:    request,
+		attributes: attributes,
+		set:        attribute.NewSet(attributes...),
+		start:      p.now(),
+	}
+}
+
+// withContext returns the context with the hooks of the trace installed,
+// in addition to any httptrace.ClientTrace already in the context.
+func (t *connTrace) withContext(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.conn != nil {
+				// the transport retries requests failing on a reused connection the server closed
+				t.pool.remove(t.conn)
+				if t.reused {
+					t.recorder.AddConnections(t.ctx, ConnectionEventClosedByPeer, 1, t.attributes)
+				}
+			}
+			t.conn = info.Conn
+			t.reused = info.Reused
+			t.putIdle = false
+			if tlsConn, ok := info.Conn.(*tls.Conn); ok {
+				t.http2 = tlsConn.ConnectionState().NegotiatedProtocol == "h2"
+			}
+			t.pool.acquire(info.Conn, t.set, info.Reused, t.start)
+			event := ConnectionEventNew
+			if info.Reused {
+				event = ConnectionEventReused
+			}
+			t.recorder.AddConnections(t.ctx, event, 1, t.attributes)
+		},
+		PutIdleConn: func(err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.conn == nil {
+				return
+			}
+			t.putIdle = true
+			if err != nil {
+				// the pool is full or the connection is broken, it is closed
+				t.pool.remove(t.conn)
+				return
+			}
+			t.pool.release(t.conn)
+		},
+	})
+}
+
+// release updates the pool once the request is done with its connection, when its response
+// failed, has no body or its body was read or closed. An HTTP/1 connection which was not
+// returned to the idle pool by then is closed.
+func (t *connTrace) release(res *http.Response, err error) {
+	t.once.Do(func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.conn == nil || t.putIdle {
+			return
+		}
+		switch {
+		case t.http2:
+			// only the stream is done, the connection stays open for other requests
+			t.pool.release(t.conn)
+		default:
+			t.pool.remove(t.conn)
+			if (err != nil && t.reused) || (res != nil && res.Close && !t.request.Close) {
+				t.recorder.AddConnections(t.ctx, ConnectionEventClosedByPeer, 1, t.attributes)
+			}
+		}
+	})
+}
+
+// releaseBody releases the connection of the request once the response body was read or closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.release()
+	}
+	return n, err
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// serverAttributes are the attributes the connections to the server of the request are reported with
+func serverAttributes(request *http.Request) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if request.URL == nil {
+		return attrs
+	}
+	if host := request.URL.Hostname(); host != "" {
+		attrs = append(attrs, semconvstable.ServerAddressKey.String(host))
+	}
+	port := request.URL.Port()
+	if port == "" {
+		switch request.URL.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconvstable.ServerPortKey.Int(p))
+	}
+	return attrs
+}
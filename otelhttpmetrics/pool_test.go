@@ -0,0 +1,134 @@
+package otelhttpmetrics_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+)
+
+// get sends a request through the client and reads the response body, releasing the connection
+func get(t *testing.T, client *http.Client, url string) {
+	t.Helper()
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+}
+
+// connections returns the active and idle connections reported to the recorder
+func connections(recorder *otelhttpmetricstest.Recorder) (active, idle int64) {
+	for _, stats := range recorder.ConnectionPool() {
+		active += stats.Active
+		idle += stats.Idle
+	}
+	return active, idle
+}
+
+func TestConnectionPoolClosedIdleConnection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	recorder := otelhttpmetricstest.NewRecorder()
+	// a transport without IdleConnTimeout keeps its idle connections until the server closes them
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(&http.Transport{}, otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithConnectionPoolMetrics())}
+
+	for i := 0; i < 3; i++ {
+		get(t, client, server.URL)
+		server.CloseClientConnections()
+		// let the transport notice that the server closed the idle connection
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if active, idle := connections(recorder); active != 0 || idle != 1 {
+		t.Errorf("got %d active and %d idle connections, want 0 and 1", active, idle)
+	}
+}
+
+func TestConnectionPoolIdleTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	clock := otelhttpmetricstest.NewClock(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC))
+	recorder := otelhttpmetricstest.NewRecorder()
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(&http.Transport{IdleConnTimeout: time.Minute},
+		otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithConnectionPoolMetrics(), otelhttpmetrics.WithClock(clock.Now))}
+
+	get(t, client, server.URL)
+	clock.Advance(time.Minute)
+	if active, idle := connections(recorder); active != 0 || idle != 1 {
+		t.Errorf("got %d active and %d idle connections within the idle timeout, want 0 and 1", active, idle)
+	}
+
+	// the transport closed the connection once it was idle for longer than the timeout
+	clock.Advance(time.Second)
+	if active, idle := connections(recorder); active != 0 || idle != 0 {
+		t.Errorf("got %d active and %d idle connections past the idle timeout, want 0 and 0", active, idle)
+	}
+}
+
+// connectionEvents returns the number of connections recorded for the event
+func connectionEvents(recorder *otelhttpmetricstest.Recorder, event string) int64 {
+	var count int64
+	for _, m := range recorder.Filter(otelhttpmetricstest.KindConnections, func(m otelhttpmetricstest.Measurement) bool { return m.Name == event }) {
+		count += m.Value
+	}
+	return count
+}
+
+func TestConnectionPoolReuse(t *testing.T) {
+	recorder := otelhttpmetricstest.NewRecorder()
+	var mu sync.Mutex
+	var activeInHandler []int64
+	entered, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		active, _ := connections(recorder)
+		mu.Lock()
+		activeInHandler = append(activeInHandler, active)
+		mu.Unlock()
+		if r.URL.Path == "/slow" {
+			close(entered)
+			<-release
+		}
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(&http.Transport{}, otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithConnectionPoolMetrics())}
+
+	// sequential requests reuse the idle connection
+	for i := 0; i < 3; i++ {
+		get(t, client, server.URL)
+	}
+	if active, idle := connections(recorder); active != 0 || idle != 1 {
+		t.Errorf("got %d active and %d idle connections, want 0 and 1", active, idle)
+	}
+	if n, reused := connectionEvents(recorder, otelhttpmetrics.ConnectionEventNew), connectionEvents(recorder, otelhttpmetrics.ConnectionEventReused); n != 1 || reused != 2 {
+		t.Errorf("got %d new and %d reused connections, want 1 and 2", n, reused)
+	}
+
+	// a request sent while the idle connection is busy dials a second one
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		get(t, client, server.URL+"/slow")
+	}()
+	<-entered
+	get(t, client, server.URL)
+	close(release)
+	wg.Wait()
+
+	if active, idle := connections(recorder); active != 0 || idle != 2 {
+		t.Errorf("got %d active and %d idle connections, want 0 and 2", active, idle)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []int64{1, 1, 1, 1, 2}; fmt.Sprint(activeInHandler) != fmt.Sprint(want) {
+		t.Errorf("got %v active connections while handling the requests, want %v", activeInHandler, want)
+	}
+}
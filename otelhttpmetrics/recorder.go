@@ -23,3 +23,92 @@ type Recorder interface {
 	// AddInflightRequests increments and decrements the number of inflight request being processed.
 	AddInflightRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue)
 }
+
+// RequestBodySizeRecorder is implemented by recorders which measure the size of the request body apart from
+// the approximate size of the request passed to ObserveHTTPRequestSize, which counts the method, the path and the headers.
+// The handler and the transport call it when the recorder passed using WithRecorder implements it.
+type RequestBodySizeRecorder interface {
+	// ObserveHTTPRequestBodySize measures the size of the body of an HTTP request in bytes.
+	ObserveHTTPRequestBodySize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue)
+}
+
+// BodyRecorder is implemented by recorders which measure the response of outgoing requests
+// beyond the headers. The transport wraps the response body to call it when the recorder
+// passed using WithRecorder implements it.
+type BodyRecorder interface {
+	// ObserveHTTPTimeToFirstByte measures the time until the first byte of the response of an HTTP request was received.
+	ObserveHTTPTimeToFirstByte(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue)
+
+	// ObserveHTTPTimeToBodyClose measures the time until the response body of an HTTP request was closed.
+	ObserveHTTPTimeToBodyClose(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue)
+}
+
+// ClientTraceRecorder is implemented by recorders which measure the phases of outgoing requests,
+// such as DNS resolution or the TLS handshake. The transport calls it when WithClientTrace is used.
+type ClientTraceRecorder interface {
+	// ObserveHTTPClientPhase measures the duration of a phase of an HTTP request, one of the Phase constants.
+	ObserveHTTPClientPhase(ctx context.Context, phase string, duration time.Duration, attributes []attribute.KeyValue)
+}
+
+// ConnectionPoolRecorder is implemented by recorders which measure the connections of the transport per server.
+// The transport calls it when WithConnectionPoolMetrics is used.
+type ConnectionPoolRecorder interface {
+	// AddConnections increments the number of connections which went through an event, one of the ConnectionEvent constants.
+	AddConnections(ctx context.Context, event string, quantity int64, attributes []attribute.KeyValue)
+
+	// ObserveConnectionPool registers the func reporting the active and idle connections of each server,
+	// to be called each time the metrics are collected.
+	ObserveConnectionPool(observe func() []ConnectionPoolStats) error
+}
+
+// RequestSummary summarizes a request once it completed, see SummaryRecorder
+type RequestSummary struct {
+	// Route is the route of the request: the route the handler matched, nonconfigured when it matched none,
+	// or the route set using ContextWithRoute for the outgoing requests, empty when none was set
+	Route  string
+	Method string
+	// Status is the status code of the response, 0 when the round trip failed
+	Status int
+	// StartTime is the time the request started at and Duration the time until the response headers were received
+	StartTime time.Time
+	Duration  time.Duration
+	// RequestSize and ResponseSize are the sizes of the bodies in bytes, zero when WithRecordSizeDisabled is used.
+	// The response size of an outgoing request is the size of the body read by the caller
+	RequestSize  int64
+	ResponseSize int64
+	// Error is the error of the failed round trip, nil for the incoming requests
+	Error error
+	// Attributes are the attributes recorded with the response by the methods of Recorder, not to be modified
+	Attributes []attribute.KeyValue
+}
+
+// SummaryRecorder is implemented by recorders which record each request at once from its summary,
+// e.g. to compute an Apdex score or to write a log line. The handler and the transport call it once per request,
+// in addition to the methods of Recorder: the transport once the response body was closed.
+// RecorderFromSummary adapts a SummaryRecorder to a Recorder.
+type SummaryRecorder interface {
+	// RecordRequest records a request once it completed.
+	RecordRequest(ctx context.Context, summary RequestSummary)
+}
+
+// wrapper is implemented by the recorders forwarding the measurements to other recorders: MultiRecorder,
+// Backend and the decorators. They implement all the optional recorder interfaces, and forward the measurements
+// to the recorders implementing them.
+type wrapper interface {
+	// wraps reports whether implements is true for one of the recorders the measurements are forwarded to.
+	wraps(implements func(Recorder) bool) bool
+}
+
+// optional returns the recorder as the optional interface T, reporting whether it implements it.
+// A wrapper implements T only when one of the recorders it forwards to does, so that the handler
+// and the transport do not measure for nothing, e.g. build summaries which no recorder records.
+func optional[T any](recorder Recorder) (T, bool) {
+	t, ok := recorder.(T)
+	if w, isWrapper := recorder.(wrapper); ok && isWrapper {
+		ok = w.wraps(func(r Recorder) bool {
+			_, ok := optional[T](r)
+			return ok
+		})
+	}
+	return t, ok
+}
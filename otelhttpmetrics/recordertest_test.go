@@ -0,0 +1,60 @@
+package otelhttpmetrics_test
+
+import (
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+	"github.com/technologize/otel-go-contrib/recordertest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRecorderConformance(t *testing.T) {
+	newOtelRecorder := func() otelhttpmetrics.Recorder {
+		recorder, err := otelhttpmetrics.NewRecorder(otelhttpmetricstest.NewReader().MeterProvider(), "")
+		if err != nil {
+			// called from the goroutines of the tests of the suite, where t.Fatal must not be used
+			panic(err)
+		}
+		return recorder
+	}
+
+	recorders := map[string]func() otelhttpmetrics.Recorder{
+		"NewRecorder": newOtelRecorder,
+		"otelhttpmetricstest": func() otelhttpmetrics.Recorder {
+			return otelhttpmetricstest.NewRecorder()
+		},
+		"MultiRecorder": func() otelhttpmetrics.Recorder {
+			recorder := otelhttpmetrics.MultiRecorder(
+				newOtelRecorder(),
+				otelhttpmetricstest.NewRecorder(),
+				otelhttpmetrics.Backend(otelhttpmetricstest.NewRecorder(),
+					otelhttpmetrics.WithBackendAttributeFilter(attribute.NewDenyKeysFilter("http.method")),
+					otelhttpmetrics.WithBackendQueue(1024),
+				),
+			)
+			t.Cleanup(func() { recorder.Close() })
+			return recorder
+		},
+		"FilterKeys": func() otelhttpmetrics.Recorder {
+			return otelhttpmetrics.FilterKeys(otelhttpmetricstest.NewRecorder(), "http.method")
+		},
+		"RenameKeys": func() otelhttpmetrics.Recorder {
+			return otelhttpmetrics.RenameKeys(otelhttpmetricstest.NewRecorder(), map[attribute.Key]attribute.Key{"http.target": "url.path"})
+		},
+		"AddStaticAttributes": func() otelhttpmetrics.Recorder {
+			return otelhttpmetrics.AddStaticAttributes(otelhttpmetricstest.NewRecorder(), attribute.String("deployment.environment", "test"))
+		},
+		"MapValues": func() otelhttpmetrics.Recorder {
+			return otelhttpmetrics.MapValues(otelhttpmetricstest.NewRecorder(), "http.method", func(value attribute.Value) attribute.Value {
+				return attribute.StringValue("_OTHER")
+			})
+		},
+	}
+	for name, newRecorder := range recorders {
+		newRecorder := newRecorder
+		t.Run(name, func(t *testing.T) {
+			recordertest.Run(t, func() recordertest.Recorder { return newRecorder() })
+		})
+	}
+}
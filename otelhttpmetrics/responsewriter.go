@@ -0,0 +1,219 @@
+package otelhttpmetrics
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps a http.ResponseWriter to capture the status code and
+// the number of bytes written. It implements none of the optional interfaces
+// of the wrapped writer itself, see newResponseWriter.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+	hijacked    bool
+}
+
+// newResponseWriter returns the responseWriter capturing what is written to w, along with the writer to pass
+// to the handler. The latter implements http.Flusher, http.Hijacker, http.Pusher and io.ReaderFrom
+// only when w implements them, so that the handler sees the same capabilities as without the middleware.
+func newResponseWriter(w http.ResponseWriter) (*responseWriter, http.ResponseWriter) {
+	rw := &responseWriter{ResponseWriter: w}
+	f, h, p, r := flusher{rw}, hijacker{rw}, pusher{rw}, readerFrom{rw}
+	var capabilities int
+	if _, ok := w.(http.Flusher); ok {
+		capabilities |= 1
+	}
+	if _, ok := w.(http.Hijacker); ok {
+		capabilities |= 2
+	}
+	if _, ok := w.(http.Pusher); ok {
+		capabilities |= 4
+	}
+	if _, ok := w.(io.ReaderFrom); ok {
+		capabilities |= 8
+	}
+	switch capabilities {
+	case 1:
+		return rw, struct {
+			*responseWriter
+			flusher
+		}{rw, f}
+	case 2:
+		return rw, struct {
+			*responseWriter
+			hijacker
+		}{rw, h}
+	case 3:
+		return rw, struct {
+			*responseWriter
+			flusher
+			hijacker
+		}{rw, f, h}
+	case 4:
+		return rw, struct {
+			*responseWriter
+			pusher
+		}{rw, p}
+	case 5:
+		return rw, struct {
+			*responseWriter
+			flusher
+			pusher
+		}{rw, f, p}
+	case 6:
+		return rw, struct {
+			*responseWriter
+			hijacker
+			pusher
+		}{rw, h, p}
+	case 7:
+		return rw, struct {
+			*responseWriter
+			flusher
+			hijacker
+			pusher
+		}{rw, f, h, p}
+	case 8:
+		return rw, struct {
+			*responseWriter
+			readerFrom
+		}{rw, r}
+	case 9:
+		return rw, struct {
+			*responseWriter
+			flusher
+			readerFrom
+		}{rw, f, r}
+	case 10:
+		return rw, struct {
+			*responseWriter
+			hijacker
+			readerFrom
+		}{rw, h, r}
+	case 11:
+		return rw, struct {
+			*responseWriter
+			flusher
+			hijacker
+			readerFrom
+		}{rw, f, h, r}
+	case 12:
+		return rw, struct {
+			*responseWriter
+			pusher
+			readerFrom
+		}{rw, p, r}
+	case 13:
+		return rw, struct {
+			*responseWriter
+			flusher
+			pusher
+			readerFrom
+		}{rw, f, p, r}
+	case 14:
+		return rw, struct {
+			*responseWriter
+			hijacker
+			pusher
+			readerFrom
+		}{rw, h, p, r}
+	case 15:
+		return rw, struct {
+			*responseWriter
+			flusher
+			hijacker
+			pusher
+			readerFrom
+		}{rw, f, h, p, r}
+	}
+	return rw, rw
+}
+
+// WriteHeader records the first final status code written. Informational 1xx codes, such as 103 Early Hints,
+// are passed through without being recorded as they precede the final status, except 101 Switching Protocols.
+func (w *responseWriter) WriteHeader(statusCode int) {
+	informational := statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols
+	if !w.wroteHeader && !informational {
+		w.status = statusCode
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Status returns the status code written to the response. If the connection
+// was hijacked without writing a header, 101 Switching Protocols is assumed.
+func (w *responseWriter) Status() int {
+	if w.wroteHeader {
+		return w.status
+	}
+	if w.hijacked {
+		return http.StatusSwitchingProtocols
+	}
+	return http.StatusOK
+}
+
+// Size returns the number of body bytes written to the response.
+func (w *responseWriter) Size() int64 {
+	return w.size
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, which is used by
+// http.ResponseController to reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// flusher passes Flush through to the wrapped writer, which implements http.Flusher.
+type flusher struct{ w *responseWriter }
+
+func (f flusher) Flush() {
+	if !f.w.wroteHeader {
+		f.w.WriteHeader(http.StatusOK)
+	}
+	f.w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// hijacker passes Hijack through to the wrapped writer, which implements http.Hijacker.
+type hijacker struct{ w *responseWriter }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := h.w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		h.w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// pusher passes Push through to the wrapped writer, which implements http.Pusher.
+type pusher struct{ w *responseWriter }
+
+func (p pusher) Push(target string, opts *http.PushOptions) error {
+	return p.w.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// readerFrom passes ReadFrom through to the wrapped writer, which implements io.ReaderFrom,
+// counting the bytes copied as written to the response.
+type readerFrom struct{ w *responseWriter }
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	if !r.w.wroteHeader {
+		r.w.WriteHeader(http.StatusOK)
+	}
+	n, err := r.w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	r.w.size += n
+	return n, err
+}
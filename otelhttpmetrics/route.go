@@ -0,0 +1,49 @@
+package otelhttpmetrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// unmatchedRoute is recorded as the route of requests which did not match any
+// pattern, so that unknown paths do not create a new time series each.
+const unmatchedRoute = "nonconfigured"
+
+// serverRoute returns the path template of the pattern that the request is
+// routed to, or an empty string when it is not known.
+func serverRoute(mux *http.ServeMux, r *http.Request) string {
+	if pattern := requestPattern(r); pattern != "" {
+		return routeFromPattern(pattern)
+	}
+	if mux != nil {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			return routeFromPattern(pattern)
+		}
+	}
+	return ""
+}
+
+// routeFromPattern strips the method and host from a ServeMux pattern such
+// as "GET example.com/users/{id}" leaving only the path template.
+func routeFromPattern(pattern string) string {
+	if idx := strings.IndexByte(pattern, '/'); idx >= 0 {
+		return pattern[idx:]
+	}
+	return pattern
+}
+
+type routeKey struct{}
+
+// ContextWithRoute returns a copy of ctx carrying the route template of an outgoing request, e.g. /users/{id}.
+// The default attributes of the transport record it as http.route in place of the path of the request,
+// custom attributes funcs can read it with RouteFromContext
+func ContextWithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// RouteFromContext returns the route template set by ContextWithRoute, or an empty string when none was set
+func RouteFromContext(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey{}).(string)
+	return route
+}
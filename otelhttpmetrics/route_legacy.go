@@ -0,0 +1,11 @@
+//go:build !go1.23
+
+package otelhttpmetrics
+
+import "net/http"
+
+// requestPattern returns an empty string as Request.Pattern is only
+// available from go1.23, the route is then looked up using the ServeMux.
+func requestPattern(_ *http.Request) string {
+	return ""
+}
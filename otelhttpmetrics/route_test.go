@@ -0,0 +1,20 @@
+package otelhttpmetrics
+
+import "testing"
+
+func TestRouteFromPattern(t *testing.T) {
+	for _, tt := range []struct {
+		pattern string
+		want    string
+	}{
+		{"/users/{id}", "/users/{id}"},
+		{"GET /users/{id}", "/users/{id}"},
+		{"example.com/users/", "/users/"},
+		{"GET example.com/users/{id...}", "/users/{id...}"},
+		{"POST /", "/"},
+	} {
+		if got := routeFromPattern(tt.pattern); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package otelhttpmetrics
+
+import (
+	"github.com/technologize/otel-go-contrib/internal/rules"
+)
+
+// The actions of the rules
+const (
+	// RuleActionInclude records the matching requests, with the attributes of the rule
+	RuleActionInclude = rules.ActionInclude
+	// RuleActionExclude does not record the matching requests
+	RuleActionExclude = rules.ActionExclude
+	// RuleActionAttributes adds the attributes of the rule to the matching requests, the default action
+	RuleActionAttributes = rules.ActionAttributes
+)
+
+// RulesConfig is the declarative form of the rules, as loaded from YAML or JSON:
+//
+//	rules:
+//	  - match:
+//	      paths: ["/healthz", "/metrics"]
+//	    action: exclude
+//	  - match:
+//	      user_agent: "(?i)bot|crawler"
+//	    attributes:
+//	      client.kind: bot
+//
+// The rules are evaluated in order. The attributes of all the matching rules are added to the
+// attributes of the request, replacing those with the same key, and the first matching rule
+// including or excluding the request decides whether it is recorded. Requests which no rule
+// excludes are recorded.
+type RulesConfig = rules.Config
+
+// RuleConfig is a rule, applying its action and attributes to the requests it matches
+type RuleConfig = rules.RuleConfig
+
+// MatchConfig selects the requests a rule applies to. A request matches when it matches every
+// field which is set, and a list matches when any of its items matches. Routes, paths and hosts
+// are globs as understood by path.Match, the regular expressions follow the regexp syntax.
+type MatchConfig = rules.MatchConfig
+
+// Rules are the compiled rules, safe for concurrent use.
+// They are set on the handler or the transport using WithRules
+type Rules = rules.Rules
+
+// RulesProvider provides the rules currently in effect, it is implemented by Rules and RulesWatcher
+type RulesProvider = rules.Provider
+
+// NewRules compiles the rules of the config, an error is returned for invalid patterns or actions
+func NewRules(config RulesConfig) (*Rules, error) {
+	return rules.New(config)
+}
+
+// ParseRules parses and compiles the rules from YAML or JSON
+func ParseRules(data []byte) (*Rules, error) {
+	return rules.Parse(data)
+}
+
+// LoadRules reads, parses and compiles the rules from a YAML or JSON file
+func LoadRules(filename string) (*Rules, error) {
+	return rules.Load(filename)
+}
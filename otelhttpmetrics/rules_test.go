@@ -0,0 +1,98 @@
+package otelhttpmetrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+)
+
+func TestParseRulesErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"invalid yaml":   "rules: [",
+		"unknown field":  "rules:\n  - match:\n      pathz: [/healthz]\n",
+		"unknown action": "rules:\n  - action: drop\n",
+		"invalid glob":   "rules:\n  - match:\n      paths: [\"/[\"]\n",
+		"invalid regexp": "rules:\n  - match:\n      path_regex: \"(\"\n",
+		"invalid header": "rules:\n  - match:\n      headers:\n        X-Debug: \"[\"\n",
+	} {
+		if _, err := otelhttpmetrics.ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: rules accepted", name)
+		}
+	}
+}
+
+func TestRulesFirstMatchWins(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		rules    string
+		recorded int64
+	}{
+		{
+			name:     "include first",
+			rules:    "rules:\n  - match:\n      paths: [/admin/*]\n    action: include\n  - match:\n      paths: [/admin/*]\n    action: exclude\n",
+			recorded: 1,
+		},
+		{
+			name:     "exclude first",
+			rules:    "rules:\n  - match:\n      paths: [/admin/*]\n    action: exclude\n  - match:\n      paths: [/admin/*]\n    action: include\n",
+			recorded: 0,
+		},
+		{
+			name:     "attributes do not decide",
+			rules:    "rules:\n  - match:\n      paths: [/admin/*]\n    attributes:\n      tier: admin\n  - match:\n      methods: [get]\n    action: exclude\n",
+			recorded: 0,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := otelhttpmetrics.ParseRules([]byte(tt.rules))
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder := otelhttpmetricstest.NewRecorder()
+			handler := otelhttpmetrics.NewHandler(http.NotFoundHandler(), otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithRules(rules))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+
+			if n := len(recorder.Filter(otelhttpmetricstest.KindRequests, nil)); int64(n) != tt.recorded {
+				t.Errorf("got %d requests recorded, want %d", n, tt.recorded)
+			}
+		})
+	}
+}
+
+func TestRulesAttributes(t *testing.T) {
+	rules, err := otelhttpmetrics.ParseRules([]byte(`
+rules:
+  - match:
+      paths: [/admin/*]
+    attributes:
+      tier: admin
+      area: backoffice
+  - match:
+      user_agent: "(?i)bot"
+    attributes:
+      tier: bot
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := otelhttpmetricstest.NewRecorder()
+	handler := otelhttpmetrics.NewHandler(http.NotFoundHandler(), otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithRules(rules))
+	request := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	request.Header.Set("User-Agent", "GoodBot/1.0")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	requests := recorder.Filter(otelhttpmetricstest.KindRequests, nil)
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests recorded, want 1", len(requests))
+	}
+	// the attributes of all the matching rules are added, the last one replacing those with the same key
+	if tier, _ := requests[0].Attributes.Value("tier"); tier.AsString() != "bot" {
+		t.Errorf("got tier %q, want bot", tier.AsString())
+	}
+	if area, _ := requests[0].Attributes.Value("area"); area.AsString() != "backoffice" {
+		t.Errorf("got area %q, want backoffice", area.AsString())
+	}
+}
@@ -0,0 +1,59 @@
+package otelhttpmetrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+	semconvstable "go.opentelemetry.io/otel/semconv/v1.21.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+func TestHandlerDuplicateSemconv(t *testing.T) {
+	reader := otelhttpmetricstest.NewReader()
+	handler := otelhttpmetrics.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}), otelhttpmetrics.WithMeterProvider(reader.MeterProvider()), otelhttpmetrics.WithSemconvMode(otelhttpmetrics.SemconvDuplicate))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", strings.NewReader("abc")))
+
+	// the stable status code is exact and the stable size is the size of the body
+	if count, sum := reader.Histogram(t, "http.server.request.body.size", semconvstable.HTTPResponseStatusCode(http.StatusMethodNotAllowed)); count != 1 || sum != 3 {
+		t.Errorf("request body size: got %d data points summing to %v, want 1 summing to 3", count, sum)
+	}
+	// the old status code is grouped and the old size approximates the whole request
+	if count, sum := reader.Histogram(t, "http.server.request_content_length", semconv.HTTPStatusCodeKey.Int(400)); count != 1 || sum <= 3 {
+		t.Errorf("request content length: got %d data points summing to %v, want 1 summing to more than 3", count, sum)
+	}
+}
+
+func TestTransportStableRequestBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+	reader := otelhttpmetricstest.NewReader()
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(server.Client().Transport,
+		otelhttpmetrics.WithMeterProvider(reader.MeterProvider()), otelhttpmetrics.WithSemconvMode(otelhttpmetrics.SemconvStable))}
+
+	for _, body := range []io.Reader{
+		strings.NewReader("abc"),
+		// the length of the body is unknown, it is sent chunked
+		io.MultiReader(strings.NewReader("abc")),
+	} {
+		res, err := client.Post(server.URL, "text/plain", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+	}
+
+	if count, sum := reader.Histogram(t, "http.client.request.body.size"); count != 2 || sum != 6 {
+		t.Errorf("request body size: got %d data points summing to %v, want 2 summing to 6", count, sum)
+	}
+}
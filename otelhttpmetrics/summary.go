@@ -0,0 +1,53 @@
+package otelhttpmetrics
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RecorderFromSummary returns a Recorder passing the summaries of the requests to the SummaryRecorder,
+// to be used with WithRecorder. Its other methods do nothing, the requests in flight are not recorded
+func RecorderFromSummary(recorder SummaryRecorder) Recorder {
+	return summaryRecorder{recorder: recorder}
+}
+
+type summaryRecorder struct {
+	recorder SummaryRecorder
+}
+
+func (r summaryRecorder) RecordRequest(ctx context.Context, summary RequestSummary) {
+	r.recorder.RecordRequest(ctx, summary)
+}
+
+func (summaryRecorder) AddRequests(context.Context, int64, []attribute.KeyValue) {}
+
+func (summaryRecorder) ObserveHTTPRequestDuration(context.Context, time.Duration, []attribute.KeyValue) {
+}
+
+func (summaryRecorder) ObserveHTTPRequestSize(context.Context, int64, []attribute.KeyValue) {}
+
+func (summaryRecorder) ObserveHTTPResponseSize(context.Context, int64, []attribute.KeyValue) {}
+
+func (summaryRecorder) AddInflightRequests(context.Context, int64, []attribute.KeyValue) {}
+
+// SummaryFromRecorder returns a SummaryRecorder recording the summaries of the requests using the methods
+// of the Recorder, e.g. to record the requests summarized by other code with an existing Recorder
+func SummaryFromRecorder(recorder Recorder) SummaryRecorder {
+	return recorderSummary{recorder: recorder}
+}
+
+type recorderSummary struct {
+	recorder Recorder
+}
+
+func (r recorderSummary) RecordRequest(ctx context.Context, summary RequestSummary) {
+	r.recorder.AddRequests(ctx, 1, summary.Attributes)
+	r.recorder.ObserveHTTPRequestSize(ctx, summary.RequestSize, summary.Attributes)
+	if recorder, ok := optional[RequestBodySizeRecorder](r.recorder); ok {
+		recorder.ObserveHTTPRequestBodySize(ctx, summary.RequestSize, summary.Attributes)
+	}
+	r.recorder.ObserveHTTPResponseSize(ctx, summary.ResponseSize, summary.Attributes)
+	r.recorder.ObserveHTTPRequestDuration(ctx, summary.Duration, summary.Attributes)
+}
@@ -0,0 +1,92 @@
+package otelhttpmetrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+)
+
+func TestSummaryRoundTrip(t *testing.T) {
+	clock := otelhttpmetricstest.NewClock(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC))
+	start := clock.Now()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		clock.Advance(time.Second)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, "ok")
+	})
+	summaries := otelhttpmetricstest.NewRecorder()
+	// the summaries are passed to a SummaryRecorder adapted to a Recorder
+	handler := otelhttpmetrics.NewHandler(mux, otelhttpmetrics.WithRecorder(otelhttpmetrics.RecorderFromSummary(summaries)), otelhttpmetrics.WithClock(clock.Now))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("hello")))
+
+	recorded := summaries.Summaries()
+	if len(recorded) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(recorded))
+	}
+	summary := recorded[0]
+	if summary.Route != "/users" || summary.Method != http.MethodPost || summary.Status != http.StatusCreated ||
+		!summary.StartTime.Equal(start) || summary.Duration != time.Second ||
+		summary.RequestSize != 5 || summary.ResponseSize != 2 || summary.Error != nil {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if measurements := summaries.Measurements(); len(measurements) != 0 {
+		t.Errorf("expected only the summary to be recorded, got %v", measurements)
+	}
+
+	// the summary recorded back with a Recorder gives the measurements of the request
+	recorder := otelhttpmetricstest.NewRecorder()
+	otelhttpmetrics.SummaryFromRecorder(recorder).RecordRequest(context.Background(), summary)
+	// the status code attribute is grouped, unlike the status of the summary
+	recorder.AssertRequestCount(t, "/users", http.StatusOK, 1)
+	recorder.AssertDurationRecorded(t, "/users", time.Second)
+	for kind, want := range map[string]int64{
+		otelhttpmetricstest.KindRequestSize:     5,
+		otelhttpmetricstest.KindRequestBodySize: 5,
+		otelhttpmetricstest.KindResponseSize:    2,
+	} {
+		if sizes := recorder.Filter(kind, nil); len(sizes) != 1 || sizes[0].Value != want {
+			t.Errorf("%s: got %v, want a single measurement of %d", kind, sizes, want)
+		}
+	}
+}
+
+func TestTransportSummary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "missing", http.StatusNotFound)
+	}))
+	defer server.Close()
+	recorder := otelhttpmetricstest.NewRecorder()
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(server.Client().Transport, otelhttpmetrics.WithRecorder(recorder))}
+
+	req, err := http.NewRequestWithContext(otelhttpmetrics.ContextWithRoute(context.Background(), "/items/:id"), http.MethodGet, server.URL+"/items/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	if n := len(recorder.Summaries()); n != 0 {
+		t.Errorf("expected the summary to wait for the body to be closed, got %d", n)
+	}
+	_ = res.Body.Close()
+
+	recorded := recorder.Summaries()
+	if len(recorded) != 1 {
+		t.Fatalf("got %d summaries, want 1", len(recorded))
+	}
+	if summary := recorded[0]; summary.Route != "/items/:id" || summary.Status != http.StatusNotFound || summary.ResponseSize != int64(len(body)) {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
@@ -0,0 +1,112 @@
+package otelhttpmetrics_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTransportTracing(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		status, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		status int
+		code   codes.Code
+	}{
+		{http.StatusOK, codes.Unset},
+		{http.StatusNotFound, codes.Error},
+		{http.StatusServiceUnavailable, codes.Error},
+	} {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			spans := tracetest.NewSpanRecorder()
+			client := &http.Client{Transport: otelhttpmetrics.NewTransport(http.DefaultTransport,
+				otelhttpmetrics.WithRecorder(otelhttpmetricstest.NewRecorder()),
+				otelhttpmetrics.WithTracing(),
+				otelhttpmetrics.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+				otelhttpmetrics.WithPropagators(propagation.TraceContext{}))}
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%d", server.URL, tt.status), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response, err := client.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = response.Body.Close()
+
+			if len(request.Header) != 0 {
+				t.Errorf("the headers of the request sent were modified: %v", request.Header)
+			}
+			ended := spans.Ended()
+			if len(ended) != 1 {
+				t.Fatalf("got %d spans, want 1", len(ended))
+			}
+			span := ended[0]
+			if span.SpanKind() != trace.SpanKindClient {
+				t.Errorf("got span kind %v, want %v", span.SpanKind(), trace.SpanKindClient)
+			}
+			if span.Status().Code != tt.code {
+				t.Errorf("got span status %v, want %v", span.Status().Code, tt.code)
+			}
+			if want := fmt.Sprintf("00-%s-%s-01", span.SpanContext().TraceID(), span.SpanContext().SpanID()); traceparent != want {
+				t.Errorf("got traceparent %q, want %q", traceparent, want)
+			}
+			attributes := attribute.NewSet(span.Attributes()...)
+			if status, _ := attributes.Value(semconv.HTTPStatusCodeKey); status.AsInt64() != int64(tt.status) {
+				t.Errorf("got status code attribute %v, want %d", status.Emit(), tt.status)
+			}
+		})
+	}
+}
+
+func TestTransportTracingError(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("read response: %w", io.ErrUnexpectedEOF)
+	})
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(base,
+		otelhttpmetrics.WithRecorder(otelhttpmetricstest.NewRecorder()),
+		otelhttpmetrics.WithTracing(),
+		otelhttpmetrics.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))))}
+
+	if _, err := client.Get("http://example.com"); err == nil {
+		t.Fatal("the round trip succeeded")
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("got %d spans, want 1", len(ended))
+	}
+	span := ended[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("got span status %v, want %v", span.Status().Code, codes.Error)
+	}
+	attributes := attribute.NewSet(span.Attributes()...)
+	if errorType, _ := attributes.Value(otelhttpmetrics.ErrorTypeKey); errorType.AsString() != otelhttpmetrics.ErrorTypeEOF {
+		t.Errorf("got error.type attribute %q, want %q", errorType.AsString(), otelhttpmetrics.ErrorTypeEOF)
+	}
+	if events := span.Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("got span events %v, want an exception", events)
+	}
+}
@@ -1,15 +1,22 @@
 package otelhttpmetrics
 
 import (
+	"context"
 	"net/http"
 	"time"
 
-	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type transport struct {
-	rt  http.RoundTripper
-	cfg *config
+	rt     http.RoundTripper
+	cfg    *config
+	pool   *connPool
+	tracer trace.Tracer
 }
 
 func NewTransport(base http.RoundTripper, options ...Option) *transport {
@@ -17,68 +24,255 @@ func NewTransport(base http.RoundTripper, options ...Option) *transport {
 		base = http.DefaultTransport
 	}
 
-	cfg := defaultConfig()
-	for _, option := range options {
-		option.apply(cfg)
+	cfg := newConfig(options)
+	if cfg.disabled {
+		return &transport{rt: base, cfg: cfg}
+	}
+	if cfg.attributes == nil {
+		cfg.attributes = attributesForMode(cfg.semconvMode, DefaultAttributes, StableAttributes)
 	}
 	if cfg.recorder == nil {
-		cfg.recorder = GetRecorder("")
+		cfg.recorder = cfg.newRecorder("http.client")
 	}
 
 	t := transport{
 		rt:  base,
 		cfg: cfg,
 	}
+	if cfg.tracing {
+		t.tracer = cfg.tracerProvider.Tracer(instrumentationName, trace.WithInstrumentationVersion(SemVersion()))
+	}
+	if poolRecorder, ok := optional[ConnectionPoolRecorder](cfg.recorder); ok && cfg.connectionPool {
+		t.pool = newConnPool(base, cfg.now)
+		if err := poolRecorder.ObserveConnectionPool(t.pool.stats); err != nil {
+			otel.Handle(err)
+		}
+	}
 
 	return &t
 }
 
-func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
-	start := time.Now()
+func (t *transport) RoundTrip(r *http.Request) (res *http.Response, err error) {
 	cfg := t.cfg
+	start := cfg.now()
 	recorder := cfg.recorder
-	if !cfg.shouldRecord(r) {
+	overrides := overridesFromContext(r.Context())
+	if cfg.disabled || overrides.skip {
 		return t.rt.RoundTrip(r)
 	}
-	reqAttributes := cfg.attributes(r)
+	ruleAttributes, record := cfg.recordRequest(RouteFromContext(r.Context()), r)
+	if !record {
+		return t.rt.RoundTrip(r)
+	}
+	reqAttributes := cfg.requestAttributes(r, overrides, ruleAttributes)
+
+	if t.tracer != nil {
+		var span trace.Span
+		r, span = t.startSpan(r, reqAttributes)
+		defer func() { endSpan(cfg, span, res, err) }()
+	}
+
+	ctx := withoutCancel(r.Context())
 
 	if cfg.recordInFlight {
-		recorder.AddInflightRequests(r.Context(), 1, reqAttributes)
-		defer recorder.AddInflightRequests(r.Context(), -1, reqAttributes)
+		recorder.AddInflightRequests(ctx, 1, reqAttributes)
+		defer recorder.AddInflightRequests(ctx, -1, reqAttributes)
+	}
+
+	var phases *clientTrace
+	phaseRecorder, ok := optional[ClientTraceRecorder](recorder)
+	if cfg.clientTrace && ok {
+		phases = newClientTrace(cfg.now)
+		r = r.WithContext(phases.withContext(r.Context()))
 	}
 
-	res, err := t.rt.RoundTrip(r)
+	var firstByte *firstByteTrace
+	bodyRecorder, ok := optional[BodyRecorder](recorder)
+	if cfg.recordDuration && ok {
+		firstByte = &firstByteTrace{now: cfg.now}
+		r = r.WithContext(firstByte.withContext(r.Context()))
+	}
+
+	var conn *connTrace
+	if t.pool != nil {
+		conn = t.pool.trace(ctx, recorder.(ConnectionPoolRecorder), r)
+		r = r.WithContext(conn.withContext(r.Context()))
+	}
+
+	var body *requestBody
+	if cfg.recordSize && r.Body != nil && r.Body != http.NoBody && r.ContentLength <= 0 {
+		// the length of the body is unknown, the bytes read by the round tripper are counted instead.
+		// Round trippers must not modify the request they are passed, the body is replaced on a shallow copy
+		body = &requestBody{ReadCloser: r.Body}
+		counted := *r
+		counted.Body = body
+		r = &counted
+	}
+
+	res, err = t.rt.RoundTrip(r)
 
 	defer func() {
-		if err != nil {
-			return
-		}
 
-		resAttributes := append(reqAttributes[0:0], reqAttributes...)
+		resCtx := ctx
+		if res != nil && res.Request != nil {
+			// a round tripper further down may have started a span in the context of the request it sent,
+			// recording with that context links the measurements to the span as exemplars
+			resCtx = withoutCancel(res.Request.Context())
+		}
 
-		if cfg.groupedStatus {
-			code := int(res.StatusCode/100) * 100
-			resAttributes = append(resAttributes, semconv.HTTPStatusCodeKey.Int(code))
+		// the response attributes get their own slice, the request attributes are still used by the caller
+		resAttributes := make([]attribute.KeyValue, len(reqAttributes), len(reqAttributes)+2)
+		copy(resAttributes, reqAttributes)
+		if err != nil {
+			// failed round trips have no status code, the class of the error is recorded instead
+			resAttributes = append(resAttributes, ErrorTypeKey.String(errorType(err)))
 		} else {
-			resAttributes = append(resAttributes, semconv.HTTPAttributesFromHTTPStatusCode(res.StatusCode)...)
+			resAttributes = append(resAttributes, cfg.statusCodeAttributes(res.StatusCode)...)
 		}
 
-		recorder.AddRequests(r.Context(), 1, resAttributes)
+		recorder.AddRequests(resCtx, 1, resAttributes)
 
+		var requestSize int64
 		if cfg.recordSize {
-			requestSize := computeApproximateRequestSize(r)
-			recorder.ObserveHTTPRequestSize(r.Context(), requestSize, resAttributes)
-			recorder.ObserveHTTPResponseSize(r.Context(), int64(res.ContentLength), resAttributes)
+			requestSize = outgoingBodySize(r, body)
+			recorder.ObserveHTTPRequestSize(resCtx, computeApproximateRequestSize(r, requestSize), resAttributes)
+			if bodySizeRecorder, ok := optional[RequestBodySizeRecorder](recorder); ok {
+				bodySizeRecorder.ObserveHTTPRequestBodySize(resCtx, requestSize, resAttributes)
+			}
 		}
 
+		duration := cfg.now().Sub(start)
 		if cfg.recordDuration {
-			recorder.ObserveHTTPRequestDuration(r.Context(), time.Since(start), resAttributes)
+			recorder.ObserveHTTPRequestDuration(resCtx, duration, resAttributes)
+			if firstByte != nil && err == nil {
+				if timeToFirstByte, ok := firstByte.since(start); ok {
+					bodyRecorder.ObserveHTTPTimeToFirstByte(resCtx, timeToFirstByte, resAttributes)
+				}
+			}
+		}
+
+		if phases != nil {
+			phases.record(resCtx, phaseRecorder, res, resAttributes)
+		}
+
+		if conn != nil {
+			res = releaseConn(conn, res, err)
+		}
+
+		var summary *RequestSummary
+		if _, ok := optional[SummaryRecorder](recorder); ok {
+			summary = &RequestSummary{
+				Route:       RouteFromContext(r.Context()),
+				Method:      r.Method,
+				StartTime:   start,
+				Duration:    duration,
+				RequestSize: requestSize,
+				Error:       err,
+				Attributes:  resAttributes,
+			}
+		}
+
+		if err == nil {
+			if summary != nil {
+				summary.Status = res.StatusCode
+			}
+			res = t.wrapBody(resCtx, res, start, resAttributes, summary)
+		} else if summary != nil {
+			recorder.(SummaryRecorder).RecordRequest(resCtx, *summary)
 		}
 	}()
-	return res, err
+	return
+}
+
+// startSpan starts the client span of the request and returns a copy of the request
+// carrying the span in its context and in the propagation headers.
+func (t *transport) startSpan(r *http.Request, attributes []attribute.KeyValue) (*http.Request, trace.Span) {
+	ctx, span := t.tracer.Start(r.Context(), r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+	r = r.Clone(ctx)
+	t.cfg.propagators.Inject(ctx, propagation.HeaderCarrier(r.Header))
+	return r, span
+}
+
+// endSpan ends the client span once the response headers were received or the round trip failed
+func endSpan(cfg *config, span trace.Span, res *http.Response, err error) {
+	defer span.End()
+	if err != nil {
+		span.SetAttributes(ErrorTypeKey.String(errorType(err)))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetAttributes(cfg.spanStatusCodeAttributes(res.StatusCode)...)
+	if res.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+	}
+}
+
+// wrapBody returns the response with its body replaced to record its size and the time
+// until it was closed, once the caller is done reading it, along with the summary of the request when not nil.
+// A copy of the response is returned, as the transport keeps a reference to the original
+// one until the body is consumed and the body would otherwise never be garbage collected.
+func (t *transport) wrapBody(ctx context.Context, res *http.Response, start time.Time, attributes []attribute.KeyValue, summary *RequestSummary) *http.Response {
+	cfg := t.cfg
+	switch {
+	case res.StatusCode == http.StatusSwitchingProtocols:
+		// the body is the connection the protocol was switched to and implements io.Writer
+		if summary != nil {
+			cfg.recorder.(SummaryRecorder).RecordRequest(ctx, *summary)
+		}
+		return res
+	case res.Body == nil || res.Body == http.NoBody:
+		attributes = append(attributes[:len(attributes):len(attributes)], BodyStateKey.String(BodyStateComplete))
+		if cfg.recordSize {
+			cfg.recorder.ObserveHTTPResponseSize(ctx, 0, attributes)
+		}
+		if summary != nil {
+			summary.Attributes = attributes
+			cfg.recorder.(SummaryRecorder).RecordRequest(ctx, *summary)
+		}
+		return res
+	}
+	if _, ok := optional[BodyRecorder](cfg.recorder); !cfg.recordSize && !(ok && cfg.recordDuration) && summary == nil {
+		return res
+	}
+	wrapped := *res
+	wrapped.Body = newResponseBody(ctx, res.Body, start, cfg, attributes, summary)
+	return &wrapped
 }
 
-func computeApproximateRequestSize(r *http.Request) int64 {
+// releaseConn releases the connection of the request right away when the response has no body,
+// otherwise a copy of the response is returned with a body releasing it once read or closed.
+func releaseConn(conn *connTrace, res *http.Response, err error) *http.Response {
+	if err != nil || res.StatusCode == http.StatusSwitchingProtocols || res.Body == nil || res.Body == http.NoBody {
+		conn.release(res, err)
+		return res
+	}
+	released := *res
+	released.Body = &releaseBody{
+		ReadCloser: res.Body,
+		release:    func() { conn.release(res, nil) },
+	}
+	return &released
+}
+
+// outgoingBodySize returns the size of the body of an outgoing request: its content length when known,
+// otherwise the bytes the round tripper read from the body by the time it returned
+func outgoingBodySize(r *http.Request, body *requestBody) int64 {
+	if body != nil {
+		return body.size.Load()
+	}
+	if r.ContentLength < 0 {
+		return 0
+	}
+	return r.ContentLength
+}
+
+// computeApproximateRequestSize approximates the size of the whole request from its path, method, protocol,
+// headers and host, plus the size of its body, as the content length is -1 for chunked bodies
+func computeApproximateRequestSize(r *http.Request, bodySize int64) int64 {
 	s := 0
 	if r.URL != nil {
 		s = len(r.URL.Path)
@@ -96,8 +290,5 @@ func computeApproximateRequestSize(r *http.Request) int64 {
 
 	// N.B. r.Form and r.MultipartForm are assumed to be included in r.URL.
 
-	if r.ContentLength != -1 {
-		s += int(r.ContentLength)
-	}
-	return int64(s)
+	return int64(s) + bodySize
 }
@@ -0,0 +1,142 @@
+package otelhttpmetrics_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics/otelhttpmetricstest"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+func TestTransportSharedAttributes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.URL.Path[1:])
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	// the attributes func returns the same slice for all the requests, with spare capacity
+	shared := make([]attribute.KeyValue, 1, 8)
+	shared[0] = attribute.String("service", "test")
+	attributes := func(*http.Request) []attribute.KeyValue { return shared }
+	recorder := otelhttpmetricstest.NewRecorder()
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(server.Client().Transport,
+		otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithAttributes(attributes))}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		status := []int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError}[i%3]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Get(server.URL + "/" + strconv.Itoa(status))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = res.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if spare := shared[:cap(shared)][1]; spare != (attribute.KeyValue{}) {
+		t.Errorf("the spare capacity of the attributes was written with %v", spare)
+	}
+	for _, status := range []int{200, 400, 500} {
+		if n := len(recorder.Filter(otelhttpmetricstest.KindRequests, func(m otelhttpmetricstest.Measurement) bool { return m.Status() == status })); n == 0 {
+			t.Errorf("no request recorded with the status %d", status)
+		}
+	}
+	if n := len(recorder.Filter(otelhttpmetricstest.KindRequests, nil)); n != 50 {
+		t.Errorf("got %d requests recorded, want 50", n)
+	}
+}
+
+func TestTransportTLSAlertErrorType(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	// the server rejects the client hello with a protocol_version alert
+	base := server.Client().Transport.(*http.Transport).Clone()
+	base.TLSClientConfig.MinVersion = tls.VersionTLS13
+	recorder := otelhttpmetricstest.NewRecorder()
+	client := &http.Client{Transport: otelhttpmetrics.NewTransport(base, otelhttpmetrics.WithRecorder(recorder))}
+
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("the handshake succeeded")
+	}
+	requests := recorder.Filter(otelhttpmetricstest.KindRequests, nil)
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests recorded, want 1", len(requests))
+	}
+	if errorType, _ := requests[0].Attributes.Value(otelhttpmetrics.ErrorTypeKey); errorType.AsString() != otelhttpmetrics.ErrorTypeTLS {
+		t.Errorf("got error.type %q, want %q", errorType.AsString(), otelhttpmetrics.ErrorTypeTLS)
+	}
+}
+
+func TestTransportContextRoute(t *testing.T) {
+	base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+	for _, tt := range []struct {
+		name string
+		mode otelhttpmetrics.SemconvMode
+		// target is the http.target recorded without a route, empty when the conventions have no path attribute
+		target string
+	}{
+		{"old", otelhttpmetrics.SemconvOld, "/items/1"},
+		{"stable", otelhttpmetrics.SemconvStable, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := otelhttpmetricstest.NewRecorder()
+			client := &http.Client{Transport: otelhttpmetrics.NewTransport(base, otelhttpmetrics.WithRecorder(recorder), otelhttpmetrics.WithSemconvMode(tt.mode))}
+			send := func(ctx context.Context) attribute.Set {
+				t.Helper()
+				recorder.Reset()
+				request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/items/1", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				response, err := client.Do(request)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_ = response.Body.Close()
+				durations := recorder.Filter(otelhttpmetricstest.KindDuration, nil)
+				if len(durations) != 1 {
+					t.Fatalf("got %d durations, want 1", len(durations))
+				}
+				return durations[0].Attributes
+			}
+
+			attributes := send(otelhttpmetrics.ContextWithRoute(context.Background(), "/items/{id}"))
+			if route, _ := attributes.Value(semconv.HTTPRouteKey); route.AsString() != "/items/{id}" {
+				t.Errorf("got http.route %q, want the route of the context", route.AsString())
+			}
+			for _, attr := range attributes.ToSlice() {
+				if strings.Contains(attr.Value.Emit(), "/items/1") {
+					t.Errorf("got the raw path in %s along with the route", attr.Key)
+				}
+			}
+
+			attributes = send(context.Background())
+			if attributes.HasValue(semconv.HTTPRouteKey) {
+				t.Error("got http.route without a route in the context")
+			}
+			if target, _ := attributes.Value(semconv.HTTPTargetKey); target.AsString() != tt.target {
+				t.Errorf("got http.target %q without a route in the context, want %q", target.AsString(), tt.target)
+			}
+		})
+	}
+}
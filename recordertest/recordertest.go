@@ -0,0 +1,338 @@
+// Package recordertest provides a conformance suite for the Recorder implementations passed to
+// otelginmetrics and otelhttpmetrics using WithRecorder.
+//
+// The suite drives the recorder through scripted traffic of the gin middleware and of the client transport,
+// including concurrent, cancelled and panicking requests, and checks the invariants expected by both packages:
+// the recorder is safe for concurrent use (run the tests with -race), accepts empty attributes, does not modify
+// the attributes passed to it, as they are passed again to the following calls, and sees the requests in flight
+// return to zero. Recorders implementing InflightReporter or RequestCountReporter are checked against the traffic.
+//
+//	func TestRecorder(t *testing.T) {
+//		recordertest.Run(t, func() recordertest.Recorder {
+//			return NewMyRecorder()
+//		})
+//	}
+package recordertest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Recorder is the interface of the recorders of otelginmetrics and otelhttpmetrics, whose methods are the same
+type Recorder interface {
+	AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue)
+	ObserveHTTPRequestDuration(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue)
+	ObserveHTTPRequestSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue)
+	ObserveHTTPResponseSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue)
+	AddInflightRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue)
+}
+
+// InflightReporter is implemented by the recorders which can report the number of requests in flight they recorded.
+// The suite checks that it returned to zero once the traffic completed
+type InflightReporter interface {
+	Inflight() int64
+}
+
+// RequestCountReporter is implemented by the recorders which can report the number of requests they recorded
+// for a http.route and a status code. The suite checks it against the scripted traffic
+type RequestCountReporter interface {
+	RequestCount(route string, status int) int64
+}
+
+const (
+	serverName = "recordertest"
+	// concurrency is the number of goroutines sending requests at the same time
+	concurrency = 8
+	// iterations is the number of times each goroutine goes through the script
+	iterations = 10
+)
+
+// request is a scripted request with the route and the status code it is expected to be recorded with
+type request struct {
+	method string
+	path   string
+	body   string
+	route  string
+	status int
+}
+
+var ginScript = []request{
+	{method: http.MethodGet, path: "/ok", route: "/ok", status: http.StatusOK},
+	{method: http.MethodPost, path: "/echo", body: "hello world", route: "/echo", status: http.StatusOK},
+	{method: http.MethodGet, path: "/users/42", route: "/users/:id", status: http.StatusNotFound},
+	{method: http.MethodGet, path: "/error", route: "/error", status: http.StatusInternalServerError},
+	{method: http.MethodGet, path: "/missing", route: "nonconfigured", status: http.StatusNotFound},
+}
+
+var transportScript = []request{
+	{method: http.MethodGet, path: "/ok", route: "/ok", status: http.StatusOK},
+	{method: http.MethodPost, path: "/echo", body: "hello world", route: "/echo", status: http.StatusOK},
+	{method: http.MethodGet, path: "/users/42", route: "/users/{id}", status: http.StatusNotFound},
+	{method: http.MethodGet, path: "/error", route: "/error", status: http.StatusInternalServerError},
+}
+
+// Run runs the conformance suite against the recorders returned by newRecorder, a new one for each test
+func Run(t *testing.T, newRecorder func() Recorder) {
+	t.Run("EmptyAttributes", func(t *testing.T) {
+		run(t, newRecorder, func(t *testing.T, s *spy) {
+			ctx := context.Background()
+			for _, attrs := range [][]attribute.KeyValue{nil, {}} {
+				s.AddInflightRequests(ctx, 1, attrs)
+				s.AddRequests(ctx, 1, attrs)
+				s.ObserveHTTPRequestDuration(ctx, 0, attrs)
+				s.ObserveHTTPRequestSize(ctx, 0, attrs)
+				s.ObserveHTTPResponseSize(ctx, 0, attrs)
+				s.AddInflightRequests(ctx, -1, attrs)
+			}
+
+			noAttributes := func(_, _ string, _ *http.Request) []attribute.KeyValue { return nil }
+			router := newRouter(s, otelginmetrics.WithAttributes(noAttributes))
+			for _, r := range ginScript {
+				serve(t, ctx, router, r)
+			}
+			client, done := newClient(s, otelhttpmetrics.WithAttributes(func(*http.Request) []attribute.KeyValue { return nil }))
+			defer done()
+			for _, r := range transportScript {
+				send(t, ctx, client, r)
+			}
+		})
+	})
+
+	t.Run("Gin", func(t *testing.T) {
+		run(t, newRecorder, func(t *testing.T, s *spy) {
+			router := newRouter(s)
+			for i := 0; i < iterations; i++ {
+				for _, r := range ginScript {
+					serve(t, context.Background(), router, r)
+				}
+			}
+			checkRequestCounts(t, s.recorder, ginScript, iterations)
+		})
+	})
+
+	t.Run("Transport", func(t *testing.T) {
+		run(t, newRecorder, func(t *testing.T, s *spy) {
+			client, done := newClient(s)
+			defer done()
+			for i := 0; i < iterations; i++ {
+				for _, r := range transportScript {
+					send(t, context.Background(), client, r)
+				}
+			}
+			checkRequestCounts(t, s.recorder, transportScript, iterations)
+		})
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		run(t, newRecorder, func(t *testing.T, s *spy) {
+			router := newRouter(s)
+			client, done := newClient(s)
+			defer done()
+			var wg sync.WaitGroup
+			for g := 0; g < concurrency; g++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < iterations; i++ {
+						for _, r := range ginScript {
+							serve(t, context.Background(), router, r)
+						}
+						for _, r := range transportScript {
+							send(t, context.Background(), client, r)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			// some routes are shared by both scripts, the requests are counted together
+			checkRequestCounts(t, s.recorder, append(append([]request(nil), ginScript...), transportScript...), concurrency*iterations)
+		})
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		run(t, newRecorder, func(t *testing.T, s *spy) {
+			cancelled, cancel := context.WithCancel(context.Background())
+			cancel()
+			router := newRouter(s)
+			for _, r := range ginScript {
+				serve(t, cancelled, router, r)
+			}
+
+			client, done := newClient(s)
+			defer done()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			req, err := http.NewRequestWithContext(otelhttpmetrics.ContextWithRoute(ctx, "/slow"), http.MethodGet, client.url+"/slow", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res, err := client.Do(req); err == nil {
+				_ = res.Body.Close()
+				t.Errorf("request to /slow: got status %d, want a timeout", res.StatusCode)
+			}
+		})
+	})
+
+	t.Run("Panicking", func(t *testing.T) {
+		run(t, newRecorder, func(t *testing.T, s *spy) {
+			router := newRouter(s, otelginmetrics.WithRecordPanics())
+			serve(t, context.Background(), router, request{method: http.MethodGet, path: "/panic", route: "/panic", status: http.StatusInternalServerError})
+			checkRequestCounts(t, s.recorder, []request{{route: "/panic", status: http.StatusInternalServerError}}, 1)
+
+			transport := otelhttpmetrics.NewTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				panic("recordertest: round trip")
+			}), otelhttpmetrics.WithRecorder(httpSpy{s}), otelhttpmetrics.WithGroupedStatusDisabled())
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Error("the panic of the round tripper was not propagated")
+					}
+				}()
+				req := httptest.NewRequest(http.MethodGet, "http://recordertest.invalid/panic", nil)
+				_, _ = transport.RoundTrip(req)
+			}()
+		})
+	})
+}
+
+// run runs the traffic against a spy forwarding to a new recorder, then checks the invariants
+func run(t *testing.T, newRecorder func() Recorder, traffic func(t *testing.T, s *spy)) {
+	t.Helper()
+	s := newSpy(newRecorder())
+	traffic(t, s)
+	for _, violation := range s.leftovers() {
+		t.Error(violation)
+	}
+	if reporter, ok := s.recorder.(InflightReporter); ok {
+		if inflight := reporter.Inflight(); inflight != 0 {
+			t.Errorf("recorder reports %d requests in flight, want 0", inflight)
+		}
+	}
+}
+
+func checkRequestCounts(t *testing.T, recorder Recorder, script []request, times int64) {
+	t.Helper()
+	reporter, ok := recorder.(RequestCountReporter)
+	if !ok {
+		return
+	}
+	want := map[request]int64{}
+	for _, r := range script {
+		want[request{route: r.route, status: r.status}] += times
+	}
+	for r, n := range want {
+		if count := reporter.RequestCount(r.route, r.status); count != n {
+			t.Errorf("recorder reports %d requests for %s with status %d, want %d", count, r.route, r.status, n)
+		}
+	}
+}
+
+func newRouter(s *spy, options ...otelginmetrics.Option) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.RecoveryWithWriter(io.Discard))
+	router.Use(otelginmetrics.Middleware(serverName, append([]otelginmetrics.Option{
+		otelginmetrics.WithRecorder(ginSpy{s}),
+		otelginmetrics.WithGroupedStatusDisabled(),
+		otelginmetrics.WithRecordHeaderSize(),
+	}, options...)...))
+	router.GET("/ok", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	router.POST("/echo", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, "text/plain", body)
+	})
+	router.GET("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+	router.GET("/error", func(c *gin.Context) {
+		_ = c.AbortWithError(http.StatusInternalServerError, errors.New("recordertest: handler error"))
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("recordertest: handler")
+	})
+	return router
+}
+
+// serve sends the request to the router, checking the status of the response
+func serve(t *testing.T, ctx context.Context, router *gin.Engine, r request) {
+	t.Helper()
+	req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body)).WithContext(ctx)
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	if rw.Code != r.status {
+		t.Errorf("%s %s: got status %d, want %d", r.method, r.path, rw.Code, r.status)
+	}
+}
+
+// client sends requests through the transport to a test server
+type client struct {
+	*http.Client
+	url string
+}
+
+func newClient(s *spy, options ...otelhttpmetrics.Option) (*client, func()) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, r.Body)
+	})
+	mux.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	server := httptest.NewServer(mux)
+	transport := otelhttpmetrics.NewTransport(server.Client().Transport, append([]otelhttpmetrics.Option{
+		otelhttpmetrics.WithRecorder(httpSpy{s}),
+		otelhttpmetrics.WithGroupedStatusDisabled(),
+		otelhttpmetrics.WithClientTrace(),
+		otelhttpmetrics.WithConnectionPoolMetrics(),
+	}, options...)...)
+	return &client{Client: &http.Client{Transport: transport}, url: server.URL}, server.Close
+}
+
+// send sends the request through the transport, checking the status of the response
+func send(t *testing.T, ctx context.Context, c *client, r request) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(otelhttpmetrics.ContextWithRoute(ctx, r.route), r.method, c.url+r.path, strings.NewReader(r.body))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		t.Errorf("%s %s: %v", r.method, r.path, err)
+		return
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+	if res.StatusCode != r.status {
+		t.Errorf("%s %s: got status %d, want %d", r.method, r.path, res.StatusCode, r.status)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,244 @@
+package recordertest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/technologize/otel-go-contrib/otelginmetrics"
+	"github.com/technologize/otel-go-contrib/otelhttpmetrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// spy forwards the calls of the middleware and the transport to the recorder under test,
+// keeping track of them to check the invariants once the traffic completed.
+// It implements the optional recorder interfaces, forwarded when the recorder under test implements them
+type spy struct {
+	recorder Recorder
+
+	mu         sync.Mutex
+	inflight   map[attribute.Distinct]*inflight
+	violations []string
+}
+
+var (
+	_ otelginmetrics.RequestBodySizeRecorder  = (*spy)(nil)
+	_ otelginmetrics.HeaderSizeRecorder       = (*spy)(nil)
+	_ otelginmetrics.PanicRecorder            = (*spy)(nil)
+	_ otelginmetrics.HandlerErrorRecorder     = (*spy)(nil)
+	_ otelhttpmetrics.RequestBodySizeRecorder = (*spy)(nil)
+	_ otelhttpmetrics.BodyRecorder            = (*spy)(nil)
+	_ otelhttpmetrics.ClientTraceRecorder     = (*spy)(nil)
+	_ otelhttpmetrics.ConnectionPoolRecorder  = (*spy)(nil)
+	_ otelginmetrics.SummaryRecorder          = ginSpy{}
+	_ otelhttpmetrics.SummaryRecorder         = httpSpy{}
+)
+
+// inflight are the requests in flight with a set of attributes
+type inflight struct {
+	attributes attribute.Set
+	quantity   int64
+}
+
+func newSpy(recorder Recorder) *spy {
+	return &spy{
+		recorder: recorder,
+		inflight: map[attribute.Distinct]*inflight{},
+	}
+}
+
+// violation reports a violation of the invariants, once however many times it happened
+func (s *spy) violation(format string, args ...interface{}) {
+	violation := fmt.Sprintf(format, args...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range s.violations {
+		if v == violation {
+			return
+		}
+	}
+	s.violations = append(s.violations, violation)
+}
+
+func (s *spy) checkQuantity(method string, quantity int64) {
+	if quantity <= 0 {
+		s.violation("%s called with quantity %d", method, quantity)
+	}
+}
+
+func (s *spy) checkSize(method string, sizeBytes int64) {
+	if sizeBytes < 0 {
+		s.violation("%s called with size %d", method, sizeBytes)
+	}
+}
+
+func (s *spy) checkDuration(method string, duration time.Duration) {
+	if duration < 0 {
+		s.violation("%s called with duration %v", method, duration)
+	}
+}
+
+func (s *spy) AddRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	s.checkQuantity("AddRequests", quantity)
+	s.forward("AddRequests", attributes, func() { s.recorder.AddRequests(ctx, quantity, attributes) })
+}
+
+func (s *spy) ObserveHTTPRequestDuration(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	s.checkDuration("ObserveHTTPRequestDuration", duration)
+	s.forward("ObserveHTTPRequestDuration", attributes, func() { s.recorder.ObserveHTTPRequestDuration(ctx, duration, attributes) })
+}
+
+func (s *spy) ObserveHTTPRequestSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	s.checkSize("ObserveHTTPRequestSize", sizeBytes)
+	s.forward("ObserveHTTPRequestSize", attributes, func() { s.recorder.ObserveHTTPRequestSize(ctx, sizeBytes, attributes) })
+}
+
+func (s *spy) ObserveHTTPResponseSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	s.checkSize("ObserveHTTPResponseSize", sizeBytes)
+	s.forward("ObserveHTTPResponseSize", attributes, func() { s.recorder.ObserveHTTPResponseSize(ctx, sizeBytes, attributes) })
+}
+
+func (s *spy) AddInflightRequests(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	set := attribute.NewSet(append([]attribute.KeyValue(nil), attributes...)...)
+	s.mu.Lock()
+	requests, ok := s.inflight[set.Equivalent()]
+	if !ok {
+		requests = &inflight{attributes: set}
+		s.inflight[set.Equivalent()] = requests
+	}
+	requests.quantity += quantity
+	total := requests.quantity
+	s.mu.Unlock()
+	if total < 0 {
+		s.violation("in flight requests with attributes %q went down to %d", set.Encoded(attribute.DefaultEncoder()), total)
+	}
+	s.forward("AddInflightRequests", attributes, func() { s.recorder.AddInflightRequests(ctx, quantity, attributes) })
+}
+
+func (s *spy) ObserveHTTPRequestHeaderSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	s.checkSize("ObserveHTTPRequestHeaderSize", sizeBytes)
+	if recorder, ok := s.recorder.(otelginmetrics.HeaderSizeRecorder); ok {
+		s.forward("ObserveHTTPRequestHeaderSize", attributes, func() { recorder.ObserveHTTPRequestHeaderSize(ctx, sizeBytes, attributes) })
+	}
+}
+
+func (s *spy) ObserveHTTPResponseHeaderSize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	s.checkSize("ObserveHTTPResponseHeaderSize", sizeBytes)
+	if recorder, ok := s.recorder.(otelginmetrics.HeaderSizeRecorder); ok {
+		s.forward("ObserveHTTPResponseHeaderSize", attributes, func() { recorder.ObserveHTTPResponseHeaderSize(ctx, sizeBytes, attributes) })
+	}
+}
+
+func (s *spy) AddPanics(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	s.checkQuantity("AddPanics", quantity)
+	if recorder, ok := s.recorder.(otelginmetrics.PanicRecorder); ok {
+		s.forward("AddPanics", attributes, func() { recorder.AddPanics(ctx, quantity, attributes) })
+	}
+}
+
+func (s *spy) AddHandlerErrors(ctx context.Context, quantity int64, attributes []attribute.KeyValue) {
+	s.checkQuantity("AddHandlerErrors", quantity)
+	if recorder, ok := s.recorder.(otelginmetrics.HandlerErrorRecorder); ok {
+		s.forward("AddHandlerErrors", attributes, func() { recorder.AddHandlerErrors(ctx, quantity, attributes) })
+	}
+}
+
+func (s *spy) ObserveHTTPRequestBodySize(ctx context.Context, sizeBytes int64, attributes []attribute.KeyValue) {
+	s.checkSize("ObserveHTTPRequestBodySize", sizeBytes)
+	if recorder, ok := s.recorder.(otelhttpmetrics.RequestBodySizeRecorder); ok {
+		s.forward("ObserveHTTPRequestBodySize", attributes, func() { recorder.ObserveHTTPRequestBodySize(ctx, sizeBytes, attributes) })
+	}
+}
+
+func (s *spy) ObserveHTTPTimeToFirstByte(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	s.checkDuration("ObserveHTTPTimeToFirstByte", duration)
+	if recorder, ok := s.recorder.(otelhttpmetrics.BodyRecorder); ok {
+		s.forward("ObserveHTTPTimeToFirstByte", attributes, func() { recorder.ObserveHTTPTimeToFirstByte(ctx, duration, attributes) })
+	}
+}
+
+func (s *spy) ObserveHTTPTimeToBodyClose(ctx context.Context, duration time.Duration, attributes []attribute.KeyValue) {
+	s.checkDuration("ObserveHTTPTimeToBodyClose", duration)
+	if recorder, ok := s.recorder.(otelhttpmetrics.BodyRecorder); ok {
+		s.forward("ObserveHTTPTimeToBodyClose", attributes, func() { recorder.ObserveHTTPTimeToBodyClose(ctx, duration, attributes) })
+	}
+}
+
+func (s *spy) ObserveHTTPClientPhase(ctx context.Context, phase string, duration time.Duration, attributes []attribute.KeyValue) {
+	s.checkDuration("ObserveHTTPClientPhase", duration)
+	if recorder, ok := s.recorder.(otelhttpmetrics.ClientTraceRecorder); ok {
+		s.forward("ObserveHTTPClientPhase", attributes, func() { recorder.ObserveHTTPClientPhase(ctx, phase, duration, attributes) })
+	}
+}
+
+func (s *spy) AddConnections(ctx context.Context, event string, quantity int64, attributes []attribute.KeyValue) {
+	s.checkQuantity("AddConnections", quantity)
+	if recorder, ok := s.recorder.(otelhttpmetrics.ConnectionPoolRecorder); ok {
+		s.forward("AddConnections", attributes, func() { recorder.AddConnections(ctx, event, quantity, attributes) })
+	}
+}
+
+func (s *spy) ObserveConnectionPool(observe func() []otelhttpmetrics.ConnectionPoolStats) error {
+	if recorder, ok := s.recorder.(otelhttpmetrics.ConnectionPoolRecorder); ok {
+		return recorder.ObserveConnectionPool(observe)
+	}
+	return nil
+}
+
+// ginSpy is the spy passed to the middleware, forwarding the summaries of otelginmetrics
+type ginSpy struct {
+	*spy
+}
+
+func (s ginSpy) RecordRequest(ctx context.Context, summary otelginmetrics.RequestSummary) {
+	s.checkSummary(summary.Duration, summary.RequestSize, summary.ResponseSize)
+	if recorder, ok := s.recorder.(otelginmetrics.SummaryRecorder); ok {
+		s.forward("RecordRequest", summary.Attributes, func() { recorder.RecordRequest(ctx, summary) })
+	}
+}
+
+// httpSpy is the spy passed to the transport, forwarding the summaries of otelhttpmetrics
+type httpSpy struct {
+	*spy
+}
+
+func (s httpSpy) RecordRequest(ctx context.Context, summary otelhttpmetrics.RequestSummary) {
+	s.checkSummary(summary.Duration, summary.RequestSize, summary.ResponseSize)
+	if recorder, ok := s.recorder.(otelhttpmetrics.SummaryRecorder); ok {
+		s.forward("RecordRequest", summary.Attributes, func() { recorder.RecordRequest(ctx, summary) })
+	}
+}
+
+func (s *spy) checkSummary(duration time.Duration, requestSize, responseSize int64) {
+	s.checkDuration("RecordRequest", duration)
+	s.checkSize("RecordRequest", requestSize)
+	s.checkSize("RecordRequest", responseSize)
+}
+
+// forward calls the recorder under test, reporting a violation when it modified the attributes,
+// which the middleware and the transport pass again to the following calls
+func (s *spy) forward(method string, attributes []attribute.KeyValue, call func()) {
+	before := append([]attribute.KeyValue(nil), attributes...)
+	call()
+	for i := range before {
+		if attributes[i] != before[i] {
+			s.violation("%s modified the attributes passed to it", method)
+			return
+		}
+	}
+}
+
+// leftovers returns the violations found, along with the attribute sets whose in flight requests did not return to zero
+func (s *spy) leftovers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	violations := append([]string(nil), s.violations...)
+	for _, requests := range s.inflight {
+		if requests.quantity != 0 {
+			violations = append(violations, fmt.Sprintf("%d in flight requests left with attributes %q",
+				requests.quantity, requests.attributes.Encoded(attribute.DefaultEncoder())))
+		}
+	}
+	return violations
+}